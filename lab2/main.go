@@ -22,21 +22,23 @@ func main() {
 	fmt.Println("grammar type of v5: ", g.ClassifyGrammar())
 
 	q0, q1, q2, q3 := "q0", "q1", "q2", "q3"
-	Q := []lfa.State{q0, q1, q2, q3}
 
-	Sigma := []byte{'a', 'b'}
-
-	Delta := make(lfa.DeltaNfa)
-	Delta.Add(q0, 'a', lfa.NewSetState(q1))
-	Delta.Add(q0, 'b', lfa.NewSetState(q0))
-	Delta.Add(q1, 'a', lfa.NewSetState(q2, q3))
-	Delta.Add(q2, 'a', lfa.NewSetState(q3))
-	Delta.Add(q2, 'b', lfa.NewSetState(q0))
-
-	Q0 := []lfa.State{q0}
-	F := []lfa.State{q3}
-
-	nfa := lfa.NewNFA(Q, Sigma, Delta, Q0, F)
+	nfa, err := lfa.NewNFABuilder().
+		Alphabet(lfa.Sym('a'), lfa.Sym('b')).
+		State(q0, q1, q2, q3).
+		Start(q0).
+		Final(q3).
+		Edge(q0, lfa.Sym('a'), q1).
+		Edge(q0, lfa.Sym('b'), q0).
+		Edge(q1, lfa.Sym('a'), q2, q3).
+		Edge(q2, lfa.Sym('a'), q3).
+		Edge(q2, lfa.Sym('b'), q0).
+		Build()
+	if err != nil {
+		fmt.Println("invalid NFA:")
+		fmt.Println(err)
+		return
+	}
 	dfa := nfa.ToDFA()
 
 	separator()
@@ -45,6 +47,17 @@ func main() {
 
 	separator()
 	fmt.Println("is DFA: ", nfa.IsDFA())
+	fmt.Println(nfa.Stats())
+
+	separator()
+	fmt.Println("The resulted DFA, trimmed:")
+	fmt.Println(dfa.Trim().Stats())
+
+	separator()
+	fmt.Println("Distinguishability table of the DFA:")
+	fmt.Print(dfa.TableFilling())
+	fmt.Println("The minimal DFA:")
+	printDFA(dfa.Minimize())
 
 	separator()
 	fmt.Println("The NFA Grammar:")
@@ -73,7 +86,7 @@ func printDFA(dfa *lfa.DFA) {
 		for _, r := range dfa.Sigma {
 			qr := dfa.Delta.Lookup(q, r)
 			if len(qr) > 0 {
-				fmt.Println("(", q, string(r), ") -> ", qr)
+				fmt.Println("(", q, r, ") -> ", qr)
 			}
 		}
 	}
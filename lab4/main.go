@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	lfa "lfa_labs/lfa"
 	"os/exec"
@@ -19,7 +20,12 @@ func showRegexProcessing(pattern string) {
 	fmt.Println("\nStep 2: Generating NFA")
 	nfa, err := lfa.CreateNFAFromRegex(pattern)
 	if err != nil {
-		fmt.Printf("Error creating NFA: %v\n", err)
+		var regexErr *lfa.RegexError
+		if errors.As(err, &regexErr) {
+			fmt.Printf("Error creating NFA:\n%s\n", regexErr.Snippet())
+		} else {
+			fmt.Printf("Error creating NFA: %v\n", err)
+		}
 		return
 	}
 
@@ -37,134 +43,106 @@ func showRegexProcessing(pattern string) {
 		fmt.Printf("NFA visualization saved to %s\n", pngFile)
 	}
 
-	// Step 3: Generate sample strings
-	fmt.Println("\nStep 3: Sample strings accepted by this regex:")
-	uniqueWords := make(map[string]bool)
-	attempts := 0
-
-	for len(uniqueWords) < 5 && attempts < 100 {
-		word, err := nfa.GenerateRandomWord(10)
-		attempts++
-		if err != nil {
-			continue
-		}
+	// Step 3: Generate a test suite covering every transition
+	suite := nfa.ToDFA().CoverageSuite()
+	fmt.Printf("\nStep 3: %d strings covering every transition of the DFA:\n", len(suite.Accepted))
+	for _, word := range suite.Accepted {
+		fmt.Printf("  + \"%s\"\n", word)
+	}
 
-		if nfa.Accept(word) && !uniqueWords[word] {
-			uniqueWords[word] = true
-			fmt.Printf("  - \"%s\"\n", word)
-		}
+	const shownMisses = 8
+	fmt.Printf("Near misses rejected by the regex (%d of %d):\n", min(shownMisses, len(suite.Rejected)), len(suite.Rejected))
+	for _, word := range suite.Rejected[:min(shownMisses, len(suite.Rejected))] {
+		fmt.Printf("  - \"%s\"\n", word)
 	}
+
+	// Step 4: Compare with the other constructions
+	fmt.Println("\nStep 4: Comparing constructions")
+	compareConstructions(pattern, nfa)
 }
 
-// Show Thompson's Construction steps specific to the regex
-func showThompsonSteps(pattern string) {
-	// Define epsilon symbol for printing
-	const epsilonSymbol = "ε"
-	// Base NFAs for all characters
-	for i, c := range pattern {
-		if isOperator(byte(c)) {
-			continue
-		}
-		fmt.Printf("  • Basic NFA for '%c' at position %d\n", c, i)
-	}
-
-	// Process specific regex constructs based on the pattern
-	if strings.Contains(pattern, "|") {
-		parts := findAlternationParts(pattern)
-		fmt.Printf("  • Creating alternation NFA for %s\n", parts)
-		fmt.Printf("    - New start state with ε-transitions to start states of both alternatives\n")
-		fmt.Printf("    - New accept state with ε-transitions from accept states of both alternatives\n")
-	}
-
-	// Handle parenthesized groups
-	groups := findGroups(pattern)
-	for i, group := range groups {
-		fmt.Printf("  • Subexpression NFA for group %d: (%s)\n", i+1, group)
-	}
-
-	// Handle quantifiers (* + ? {n})
-	for i := 0; i < len(pattern); i++ {
-		if i+1 < len(pattern) {
-			c := pattern[i]
-			next := pattern[i+1]
-			if !isOperator(byte(c)) && isQuantifier(byte(next)) {
-				switch next {
-				case '*':
-					fmt.Printf("  • Kleene star (*) applied to '%c': creates loops with ε-transitions\n", c)
-				case '+':
-					fmt.Printf("  • Plus (+) applied to '%c': at least one occurrence\n", c)
-				case '?':
-					fmt.Printf("  • Optional (?) applied to '%c': adds ε-transition bypass\n", c)
-				case '{':
-					// Find closing brace
-					end := strings.Index(pattern[i+1:], "}") + i + 1
-					if end > i {
-						fmt.Printf("  • Repetition %s applied to '%c': duplicating the NFA\n",
-							pattern[i+1:end+1], c)
-					}
+// compareConstructions prints the size of the automata built from the same
+// regex by Thompson's construction, subset construction, Glushkov's position
+// automaton, the followpos DFA and Brzozowski derivatives
+func compareConstructions(pattern string, thompson *lfa.NFA) {
+	regex := lfa.NewRegex(pattern)
+	node, err := regex.ParseAST()
+	if err != nil {
+		fmt.Printf("Error parsing regex: %v\n", err)
+		return
+	}
+
+	glushkov, err := regex.Glushkov(node)
+	if err != nil {
+		fmt.Printf("Error building position automaton: %v\n", err)
+		return
+	}
+	followpos, err := regex.PositionDFA(node)
+	if err != nil {
+		fmt.Printf("Error building followpos DFA: %v\n", err)
+		return
+	}
+	derivatives := regex.CompileDFA(node)
+	subset := thompson.ToDFA()
+
+	fmt.Printf("  %-28s %7s %12s %9s\n", "Construction", "States", "Transitions", "ε-moves")
+	row := func(name string, states int, delta lfa.DeltaNfa) {
+		edges, epsilon := 0, 0
+		for _, transitions := range delta {
+			for symbol, next := range transitions {
+				if symbol.IsEpsilon() {
+					epsilon += len(next)
+				} else {
+					edges += len(next)
 				}
 			}
 		}
+		fmt.Printf("  %-28s %7d %12d %9d\n", name, states, edges, epsilon)
 	}
-
-	// Handle concatenation
-	fmt.Println("  • Concatenating NFAs by adding ε-transitions between accept and start states")
-
-	// Add pattern-specific insights
-	if strings.Contains(pattern, "*") {
-		fmt.Println("  • Creating Kleene star NFAs: ε-transitions allow 0 or more repetitions")
-	}
-
-	if strings.Contains(pattern, "(") && strings.Contains(pattern, ")") {
-		fmt.Println("  • Connecting group NFAs by ε-transitions to main NFA")
+	row("Thompson NFA", len(thompson.Q), thompson.Delta)
+	row("Subset construction DFA", len(subset.Q), subset.ToNFA().Delta)
+	row("Glushkov position NFA", len(glushkov.Q), glushkov.Delta)
+	row("Followpos DFA", len(followpos.Q), followpos.ToNFA().Delta)
+	row("Brzozowski derivative DFA", len(derivatives.Q), derivatives.ToNFA().Delta)
+
+	// Bounded repetitions keep a single copy of their body with a counter
+	counting, err := regex.CompileCounting(node)
+	if err != nil {
+		fmt.Printf("Error building counting NFA: %v\n", err)
+		return
 	}
-
-	// Add pattern-specific final steps
-	switch pattern {
-	case "(a|b)(c|d)E+G?":
-		fmt.Println("  • (a|b) alternation → (c|d) alternation → 'E+' (one or more) → 'G?' (optional)")
-	case "P(Q|R|S)T(U|V|W|X)*Z+":
-		fmt.Println("  • 'P' NFA → (Q|R|S) alternation → 'T' NFA → (U|V|W|X)* (zero or more) → 'Z+' (one or more)")
-	case "1(0|1)*2(3|4){5}36":
-		fmt.Println("  • '1' NFA → (0|1)* (zero or more) → '2' NFA → (3|4){5} (exactly 5 times) → '3' NFA → '6' NFA")
+	fmt.Printf("  %-28s %7d   counters: %d\n", "Counting NFA", counting.NumStates(), counting.NumCounters())
+
+	// The antichain check confirms the constructions agree without
+	// determinizing either automaton
+	if equivalent, word := lfa.EquivalentNFA(thompson, glushkov); equivalent {
+		fmt.Println("  Thompson and Glushkov NFAs accept the same language")
+	} else {
+		fmt.Printf("  Thompson and Glushkov NFAs differ on %q\n", word)
 	}
 }
 
-// Helper functions
-func isOperator(c byte) bool {
-	return c == '(' || c == ')' || c == '|' || c == '*' || c == '+' || c == '?' || c == '{' || c == '}'
-}
-
-func isQuantifier(c byte) bool {
-	return c == '*' || c == '+' || c == '?' || c == '{'
-}
+// Show the steps of Thompson's construction as recorded by the regex
+// compiler, writing a DOT snapshot of the automaton after every step
+func showThompsonSteps(pattern string) {
+	regex := lfa.NewRegex(pattern)
+	node, err := regex.ParseAST()
+	if err != nil {
+		fmt.Printf("Error parsing regex: %v\n", err)
+		return
+	}
+	regex.Compile(node)
 
-func findGroups(pattern string) []string {
-	var groups []string
-	depth := 0
-	start := -1
+	for i, step := range regex.Steps() {
+		fmt.Printf("  %2d. %s\n", i+1, step)
 
-	for i, c := range pattern {
-		if c == '(' {
-			if depth == 0 {
-				start = i + 1
-			}
-			depth++
-		} else if c == ')' {
-			depth--
-			if depth == 0 && start != -1 {
-				groups = append(groups, pattern[start:i])
-				start = -1
-			}
+		dotFile := fmt.Sprintf("regex_step_%s_%02d.dot", sanitizeFilename(pattern), i+1)
+		if err := step.NFA.ToDOT(dotFile); err != nil {
+			fmt.Printf("Error generating DOT file: %v\n", err)
+			return
 		}
 	}
-
-	return groups
-}
-
-func findAlternationParts(pattern string) string {
-	// Simplified - just returns the pattern with highlighted alternation
-	return strings.Replace(pattern, "|", "←|→", 1)
+	fmt.Printf("  DOT snapshots saved to regex_step_%s_*.dot\n", sanitizeFilename(pattern))
 }
 
 // Helper function to create a valid filename from a regex pattern
@@ -195,4 +173,69 @@ func main() {
 	for _, pattern := range patterns {
 		showRegexProcessing(pattern)
 	}
+
+	classifyWords(patterns, []string{"acEG", "bdEEE", "PRTUZ", "PQTZZ", "1023434336", "12333336", "acG"})
+	scanKeywords([]string{"he", "she", "his", "hers"}, "ushers and his shed")
+	suggestWords(patterns[0], []string{"acGE", "bEG", "adEEGx"}, 1)
+}
+
+// suggestWords lists the words of the pattern's language within k edits of
+// each misspelled word, transpositions included
+func suggestWords(pattern string, words []string, k int) {
+	fmt.Printf("\n=== Suggestions from %s within %d edit ===\n\n", pattern, k)
+	language, err := lfa.CreateNFAFromRegex(pattern)
+	if err != nil {
+		fmt.Printf("Error creating NFA: %v\n", err)
+		return
+	}
+
+	for _, word := range words {
+		levenshtein := lfa.DamerauLevenshteinNFA(word, k, language.Sigma)
+		fmt.Printf("  %-8s %s\n", word, strings.Join(lfa.NearMatches(levenshtein, language), ", "))
+	}
+}
+
+// scanKeywords builds the Aho–Corasick automaton of the keywords and lists
+// its failure links and the occurrences found in text
+func scanKeywords(keywords []string, text string) {
+	fmt.Printf("\n=== Aho–Corasick automaton for %s ===\n\n", strings.Join(keywords, ", "))
+	ac, err := lfa.NewAhoCorasick(keywords, lfa.Alphabet{lfa.Range('a', 'z'), lfa.Sym(' ')})
+	if err != nil {
+		fmt.Printf("Error building automaton: %v\n", err)
+		return
+	}
+
+	fmt.Printf("%d states, failure links:", len(ac.DFA.Q))
+	for _, q := range ac.DFA.Q {
+		if f, ok := ac.Failure(q); ok && f != ac.DFA.Q0 {
+			fmt.Printf(" %s→%s", q, f)
+		}
+	}
+	fmt.Printf("\nOccurrences in %q:\n", text)
+	for _, o := range ac.FindAll(text) {
+		fmt.Printf("  %-5s at [%d, %d)\n", keywords[o.Keyword], o.Start, o.End)
+	}
+}
+
+// classifyWords reports which patterns accept each word, matching all of
+// them in a single pass with a regex set
+func classifyWords(patterns, words []string) {
+	fmt.Printf("\n=== Classifying words against all patterns ===\n\n")
+	set, err := lfa.NewRegexSet(patterns...)
+	if err != nil {
+		fmt.Printf("Error building regex set: %v\n", err)
+		return
+	}
+
+	for _, word := range words {
+		matched := make([]string, 0)
+		for _, i := range set.Matches(word) {
+			matched = append(matched, set.Patterns()[i])
+		}
+		if len(matched) == 0 {
+			fmt.Printf("  %-12s no pattern\n", word)
+		} else {
+			fmt.Printf("  %-12s %s\n", word, strings.Join(matched, ", "))
+		}
+	}
 }
@@ -2,7 +2,7 @@
 go run .
 
 # Generate PNG from DOT files
-for file in regex_nfa_*.dot; do
+for file in regex_nfa_*.dot regex_step_*.dot; do
   png_file="${file%.dot}.png"
   dot -Tpng "$file" -o "$png_file" && echo "Generated $png_file"
 done
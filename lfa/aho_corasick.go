@@ -0,0 +1,171 @@
+package lfa
+
+import (
+	"fmt"
+	"sort"
+	"unicode/utf8"
+)
+
+// AhoCorasick is the dictionary automaton of Aho and Corasick: the trie of
+// the keywords, where a missing edge falls back along failure links to the
+// longest proper suffix of the current prefix that is still in the trie. The
+// failure links are resolved at build time, so the automaton is a complete
+// DFA over the alphabet whose final states are the prefixes ending with a
+// keyword: it accepts exactly the texts that end with a keyword.
+type AhoCorasick struct {
+	DFA      *DFA
+	keywords []string
+	fail     map[State]State
+	output   map[State][]int
+}
+
+// Occurrence is a keyword found in a text, at byte offsets [Start, End)
+type Occurrence struct {
+	Keyword    int
+	Start, End int
+}
+
+// NewAhoCorasick builds the automaton of the keywords over the alphabet.
+// States are named q0 (the root), q1, ... in breadth-first order of the trie.
+func NewAhoCorasick(keywords []string, alphabet Alphabet) (*AhoCorasick, error) {
+	children := []map[rune]int{{}}
+	own := [][]int{nil}
+
+	for i, keyword := range keywords {
+		if keyword == "" {
+			return nil, fmt.Errorf("keyword %d is empty", i)
+		}
+		node := 0
+		for _, c := range keyword {
+			if !alphabet.Contains(c) {
+				return nil, fmt.Errorf("keyword %q uses %q, which is not in the alphabet %s", keyword, c, alphabet)
+			}
+			next, ok := children[node][c]
+			if !ok {
+				children = append(children, map[rune]int{})
+				own = append(own, nil)
+				next = len(children) - 1
+				children[node][c] = next
+			}
+			node = next
+		}
+		own[node] = append(own[node], i)
+	}
+
+	// Keyword runes become single pieces of the alphabet, every other piece
+	// leads back to the root
+	labels := append(Alphabet{}, alphabet.Normalize()...)
+	for _, keyword := range keywords {
+		for _, c := range keyword {
+			labels = append(labels, Sym(c))
+		}
+	}
+	pieces := splitRanges(labels)
+
+	// Breadth-first order, with children sorted by rune
+	order := []int{0}
+	for i := 0; i < len(order); i++ {
+		runes := make([]rune, 0, len(children[order[i]]))
+		for c := range children[order[i]] {
+			runes = append(runes, c)
+		}
+		sort.Slice(runes, func(a, b int) bool { return runes[a] < runes[b] })
+		for _, c := range runes {
+			order = append(order, children[order[i]][c])
+		}
+	}
+
+	ac := &AhoCorasick{
+		keywords: append([]string{}, keywords...),
+		fail:     make(map[State]State),
+		output:   make(map[State][]int),
+	}
+	names := make([]State, len(children))
+	for i, node := range order {
+		names[node] = fmt.Sprintf("q%d", i)
+	}
+
+	fail := make([]int, len(children))
+	trans := make([][]int, len(children))
+	states := make([]State, 0, len(order))
+	final := make([]State, 0)
+	delta := make(DeltaDFA)
+
+	for _, node := range order {
+		// The failure link of a child is the goto of the parent's failure
+		// link, known since the parent's link is nearer to the root
+		trans[node] = make([]int, len(pieces))
+		for p, piece := range pieces {
+			child, ok := children[node][piece.Lo]
+			switch {
+			case ok && piece.Lo == piece.Hi:
+				if node != 0 {
+					fail[child] = trans[fail[node]][p]
+				}
+				trans[node][p] = child
+			case node == 0:
+				trans[node][p] = 0
+			default:
+				trans[node][p] = trans[fail[node]][p]
+			}
+			delta.Add(names[node], piece, names[trans[node][p]])
+		}
+
+		output := append([]int{}, own[node]...)
+		if node != 0 {
+			output = append(output, ac.output[names[fail[node]]]...)
+			ac.fail[names[node]] = names[fail[node]]
+		}
+		if len(output) > 0 {
+			ac.output[names[node]] = output
+			final = append(final, names[node])
+		}
+		states = append(states, names[node])
+	}
+
+	ac.DFA = NewDFA(states, Alphabet(pieces), delta, names[0], final)
+	return ac, nil
+}
+
+// Keywords returns the keywords of the automaton
+func (ac *AhoCorasick) Keywords() []string {
+	return append([]string{}, ac.keywords...)
+}
+
+// Failure returns the failure link of a state: the state of the longest
+// proper suffix of its prefix in the trie. The root has none.
+func (ac *AhoCorasick) Failure(state State) (State, bool) {
+	f, ok := ac.fail[state]
+	return f, ok
+}
+
+// Output returns the indices of the keywords recognized in a state, the
+// longest first
+func (ac *AhoCorasick) Output(state State) []int {
+	return ac.output[state]
+}
+
+// FindAll scans the text once and returns every occurrence of every keyword,
+// overlapping ones included, ordered by end offset and then from the longest.
+// Runes outside the alphabet cannot be part of a keyword and send the scan
+// back to the root.
+func (ac *AhoCorasick) FindAll(text string) []Occurrence {
+	occurrences := make([]Occurrence, 0)
+	state := ac.DFA.Q0
+
+	for pos := 0; pos < len(text); {
+		c, size := utf8.DecodeRuneInString(text[pos:])
+		pos += size
+
+		state = ac.DFA.Delta.LookupRune(state, c)
+		if state == "" {
+			state = ac.DFA.Q0
+			continue
+		}
+		for _, k := range ac.output[state] {
+			occurrences = append(occurrences, Occurrence{Keyword: k, Start: pos - len(ac.keywords[k]), End: pos})
+		}
+	}
+
+	return occurrences
+}
@@ -0,0 +1,101 @@
+package lfa
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// naiveOccurrences finds the keywords at every end offset, longest first
+func naiveOccurrences(keywords []string, text string) []Occurrence {
+	occurrences := make([]Occurrence, 0)
+	for end := 1; end <= len(text); end++ {
+		found := make([]Occurrence, 0)
+		for k, keyword := range keywords {
+			if strings.HasSuffix(text[:end], keyword) {
+				found = append(found, Occurrence{Keyword: k, Start: end - len(keyword), End: end})
+			}
+		}
+		// Longest first, keeping the keyword order for equal lengths
+		for i := 1; i < len(found); i++ {
+			for j := i; j > 0 && found[j].Start < found[j-1].Start; j-- {
+				found[j], found[j-1] = found[j-1], found[j]
+			}
+		}
+		occurrences = append(occurrences, found...)
+	}
+	return occurrences
+}
+
+func TestAhoCorasickFindAll(t *testing.T) {
+	keywords := []string{"he", "she", "his", "hers"}
+	ac, err := NewAhoCorasick(keywords, Alphabet{Range('a', 'z')})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := ac.FindAll("ushers")
+	want := []Occurrence{{1, 1, 4}, {0, 2, 4}, {3, 2, 6}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("FindAll(ushers) = %v, expected %v", got, want)
+	}
+
+	// The trie of the classic example has 10 states
+	if len(ac.DFA.Q) != 10 {
+		t.Errorf("expected 10 states, got %d", len(ac.DFA.Q))
+	}
+	if _, ok := ac.Failure(ac.DFA.Q0); ok {
+		t.Error("the root has no failure link")
+	}
+
+	for _, text := range []string{"", "hishershe", "ahishers", "sheshe", "h", "xyz"} {
+		if got, want := ac.FindAll(text), naiveOccurrences(keywords, text); !reflect.DeepEqual(got, want) {
+			t.Errorf("FindAll(%q) = %v, expected %v", text, got, want)
+		}
+	}
+}
+
+func TestAhoCorasickDFA(t *testing.T) {
+	keywords := []string{"ab", "b", "bab", "aab"}
+	ac, err := NewAhoCorasick(keywords, NewAlphabet('a', 'b', 'c'))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The DFA is complete and accepts the texts ending with a keyword
+	for _, q := range ac.DFA.Q {
+		for _, c := range "abc" {
+			if ac.DFA.Delta.LookupRune(q, c) == "" {
+				t.Fatalf("no transition from %s on %c", q, c)
+			}
+		}
+	}
+	for _, w := range allWords("abc", 6) {
+		endsWithKeyword := false
+		for _, k := range keywords {
+			endsWithKeyword = endsWithKeyword || strings.HasSuffix(w, k)
+		}
+		if ac.DFA.Accept(w) != endsWithKeyword {
+			t.Fatalf("DFA on %q = %v, expected %v", w, ac.DFA.Accept(w), endsWithKeyword)
+		}
+		if got, want := ac.FindAll(w), naiveOccurrences(keywords, w); !reflect.DeepEqual(got, want) {
+			t.Fatalf("FindAll(%q) = %v, expected %v", w, got, want)
+		}
+	}
+
+	// Runes outside the alphabet restart the scan
+	got := ac.FindAll("aéb")
+	want := []Occurrence{{1, 3, 4}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindAll(aéb) = %v, expected %v", got, want)
+	}
+}
+
+func TestAhoCorasickErrors(t *testing.T) {
+	if _, err := NewAhoCorasick([]string{"ab", ""}, NewAlphabet('a', 'b')); err == nil {
+		t.Error("empty keywords should be rejected")
+	}
+	if _, err := NewAhoCorasick([]string{"abc"}, NewAlphabet('a', 'b')); err == nil {
+		t.Error("keywords outside the alphabet should be rejected")
+	}
+}
@@ -0,0 +1,259 @@
+package lfa
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Buchi is a nondeterministic Büchi automaton. It stores its states and
+// transitions as an NFA, but runs over infinite words: a run is accepting
+// when it visits a state of F infinitely often. The NFA is a named field
+// rather than embedded, so that finite-word methods such as Accept are not
+// mistaken for Büchi acceptance. Büchi automata are expected to be
+// epsilon-free; epsilon transitions are not followed.
+type Buchi struct {
+	Automaton *NFA
+}
+
+// NewBuchi creates a Büchi automaton from the usual NFA components
+func NewBuchi(Q []State, Sigma Alphabet, Delta DeltaNfa, Q0 []State, F []State) *Buchi {
+	return &Buchi{Automaton: NewNFA(Q, Sigma, Delta, Q0, F)}
+}
+
+// lassoEdge is an edge of the graph explored by the emptiness check
+type lassoEdge[N comparable] struct {
+	symbol RuneRange
+	to     N
+}
+
+// findLasso looks for a reachable accepting cycle with the nested depth-first
+// search of Courcoubetis et al. It returns the labels of a path from a start
+// node to an accepting node and of a cycle through that node.
+func findLasso[N comparable](starts []N, succ func(N) []lassoEdge[N], accepting func(N) bool) ([]RuneRange, []RuneRange, bool) {
+	outerVisited := make(map[N]bool)
+	innerVisited := make(map[N]bool)
+
+	var (
+		prefix []RuneRange
+		cycle  []RuneRange
+		seed   N
+	)
+
+	// inner searches for seed again; path holds the labels from seed so far
+	var inner func(n N, path []RuneRange) bool
+	inner = func(n N, path []RuneRange) bool {
+		for _, e := range succ(n) {
+			next := append(append([]RuneRange{}, path...), e.symbol)
+			if e.to == seed {
+				cycle = next
+				return true
+			}
+			if !innerVisited[e.to] {
+				innerVisited[e.to] = true
+				if inner(e.to, next) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	// outer visits nodes depth-first and starts the inner search from every
+	// accepting node in post-order
+	var outer func(n N, path []RuneRange) bool
+	outer = func(n N, path []RuneRange) bool {
+		outerVisited[n] = true
+		for _, e := range succ(n) {
+			if !outerVisited[e.to] {
+				if outer(e.to, append(append([]RuneRange{}, path...), e.symbol)) {
+					return true
+				}
+			}
+		}
+
+		if accepting(n) {
+			seed = n
+			if inner(n, nil) {
+				prefix = path
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, s := range starts {
+		if !outerVisited[s] && outer(s, nil) {
+			return prefix, cycle, true
+		}
+	}
+
+	return nil, nil, false
+}
+
+// labelsToWord picks a representative rune from every label
+func labelsToWord(labels []RuneRange) string {
+	word := make([]rune, 0, len(labels))
+	for _, l := range labels {
+		word = append(word, l.Lo)
+	}
+	return string(word)
+}
+
+// successors lists the non-epsilon edges leaving q in a stable order
+func (b *Buchi) successors(q State) []lassoEdge[State] {
+	edges := make([]lassoEdge[State], 0)
+	for symbol, nextStates := range b.Automaton.Delta[q] {
+		if symbol.IsEpsilon() {
+			continue
+		}
+		for nextState := range nextStates {
+			edges = append(edges, lassoEdge[State]{symbol, nextState})
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].symbol.Lo != edges[j].symbol.Lo {
+			return edges[i].symbol.Lo < edges[j].symbol.Lo
+		}
+		return edges[i].to < edges[j].to
+	})
+	return edges
+}
+
+// IsEmpty checks whether the automaton accepts no infinite word. When the
+// language is not empty it returns a lasso witness: the automaton accepts
+// prefix·cycle^ω.
+func (b *Buchi) IsEmpty() (empty bool, prefix, cycle string) {
+	starts := append([]State{}, b.Automaton.Q0...)
+	sort.Strings(starts)
+
+	p, c, found := findLasso(starts, b.successors, func(q State) bool {
+		return contains(b.Automaton.F, q)
+	})
+	if !found {
+		return true, "", ""
+	}
+
+	return false, labelsToWord(p), labelsToWord(c)
+}
+
+// AcceptLasso checks whether the ultimately periodic word u·v^ω is accepted.
+// The run is explored on the product of the automaton with the positions of
+// the lasso u·v, where reading the last symbol of v loops back to the start
+// of v.
+func (b *Buchi) AcceptLasso(u, v string) (bool, error) {
+	if len(v) == 0 {
+		return false, fmt.Errorf("the periodic part of an infinite word must not be empty")
+	}
+
+	word := []rune(u + v)
+	loop := len([]rune(u))
+
+	type position struct {
+		state State
+		index int
+	}
+
+	succ := func(p position) []lassoEdge[position] {
+		next := p.index + 1
+		if next == len(word) {
+			next = loop
+		}
+
+		edges := make([]lassoEdge[position], 0)
+		for _, e := range b.successors(p.state) {
+			if e.symbol.Contains(word[p.index]) {
+				edges = append(edges, lassoEdge[position]{Sym(word[p.index]), position{e.to, next}})
+			}
+		}
+		return edges
+	}
+
+	starts := make([]position, 0, len(b.Automaton.Q0))
+	for _, q := range b.Automaton.Q0 {
+		starts = append(starts, position{q, 0})
+	}
+
+	// Positions of u cannot be revisited, so only the loop can carry F
+	_, _, found := findLasso(starts, succ, func(p position) bool {
+		return p.index >= loop && contains(b.Automaton.F, p.state)
+	})
+
+	return found, nil
+}
+
+// IntersectBuchi builds a Büchi automaton accepting the infinite words
+// accepted by both a and b. States are triples (p,q,i): the flag i records
+// which automaton is awaited to visit its final states next, and switches
+// from 1 to 2 after an F of a and back after an F of b, so visiting the
+// accepting copy 1 infinitely often means both were visited infinitely often.
+func IntersectBuchi(a, b *Buchi) *Buchi {
+	type triple struct {
+		p, q State
+		i    int
+	}
+	name := func(t triple) State {
+		return fmt.Sprintf("(%s,%s,%d)", t.p, t.q, t.i)
+	}
+
+	states := make([]State, 0)
+	final := make([]State, 0)
+	start := make([]State, 0)
+	delta := make(DeltaNfa)
+
+	seen := make(map[triple]bool)
+	queue := make([]triple, 0)
+	visit := func(t triple) {
+		if seen[t] {
+			return
+		}
+		seen[t] = true
+		queue = append(queue, t)
+		states = append(states, name(t))
+		if t.i == 1 && contains(a.Automaton.F, t.p) {
+			final = append(final, name(t))
+		}
+	}
+
+	for _, p := range a.Automaton.Q0 {
+		for _, q := range b.Automaton.Q0 {
+			t := triple{p, q, 1}
+			visit(t)
+			start = append(start, name(t))
+		}
+	}
+
+	for len(queue) > 0 {
+		t := queue[0]
+		queue = queue[1:]
+
+		nextFlag := t.i
+		if t.i == 1 && contains(a.Automaton.F, t.p) {
+			nextFlag = 2
+		} else if t.i == 2 && contains(b.Automaton.F, t.q) {
+			nextFlag = 1
+		}
+
+		for _, ea := range a.successors(t.p) {
+			for _, eb := range b.successors(t.q) {
+				symbol, ok := ea.symbol.intersect(eb.symbol)
+				if !ok {
+					continue
+				}
+				next := triple{ea.to, eb.to, nextFlag}
+				visit(next)
+				delta.Add(name(t), symbol, NewSetState(name(next)))
+			}
+		}
+	}
+
+	sigma := make(Alphabet, 0)
+	for _, ra := range a.Automaton.Sigma.Normalize() {
+		for _, rb := range b.Automaton.Sigma.Normalize() {
+			if r, ok := ra.intersect(rb); ok {
+				sigma = append(sigma, r)
+			}
+		}
+	}
+
+	return NewBuchi(states, sigma, delta, start, final)
+}
@@ -0,0 +1,81 @@
+package lfa
+
+import (
+	"testing"
+)
+
+// infinitelyMany accepts the infinite words over {a,b} containing the symbol
+// c infinitely often
+func infinitelyMany(c rune, prefix string) *Buchi {
+	other := 'a' + 'b' - c
+	wait, seen := prefix+"0", prefix+"1"
+
+	delta := make(DeltaNfa)
+	delta.Add(wait, Sym(c), NewSetState(seen))
+	delta.Add(wait, Sym(other), NewSetState(wait))
+	delta.Add(seen, Sym(c), NewSetState(seen))
+	delta.Add(seen, Sym(other), NewSetState(wait))
+
+	return NewBuchi([]State{wait, seen}, NewAlphabet('a', 'b'), delta, []State{wait}, []State{seen})
+}
+
+func TestBuchiAcceptLasso(t *testing.T) {
+	b := infinitelyMany('a', "q")
+
+	cases := []struct {
+		u, v   string
+		accept bool
+	}{
+		{"b", "a", true},
+		{"", "ab", true},
+		{"aaaa", "b", false},
+		{"", "b", false},
+		{"bbb", "bba", true},
+	}
+	for _, c := range cases {
+		got, err := b.AcceptLasso(c.u, c.v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.accept {
+			t.Errorf("%s(%s)^ω: got %v, want %v", c.u, c.v, got, c.accept)
+		}
+	}
+
+	if _, err := b.AcceptLasso("a", ""); err == nil {
+		t.Error("expected an error for an empty periodic part")
+	}
+}
+
+func TestBuchiEmptinessAndIntersection(t *testing.T) {
+	inf := IntersectBuchi(infinitelyMany('a', "p"), infinitelyMany('b', "q"))
+
+	empty, u, v := inf.IsEmpty()
+	if empty {
+		t.Fatal("intersection of 'infinitely many a' and 'infinitely many b' should not be empty")
+	}
+	t.Logf("witness: %s(%s)^ω", u, v)
+
+	for _, b := range []*Buchi{inf, infinitelyMany('a', "p"), infinitelyMany('b', "q")} {
+		if ok, _ := b.AcceptLasso(u, v); !ok {
+			t.Fatalf("witness %s(%s)^ω is not accepted", u, v)
+		}
+	}
+	if ok, _ := inf.AcceptLasso("", "a"); ok {
+		t.Fatal("a^ω should not be in the intersection")
+	}
+
+	// Eventually only b: never intersects with infinitely many a
+	delta := make(DeltaNfa)
+	delta.Add("r0", Range('a', 'b'), NewSetState("r0"))
+	delta.Add("r0", Sym('b'), NewSetState("r1"))
+	delta.Add("r1", Sym('b'), NewSetState("r1"))
+	finitelyManyA := NewBuchi([]State{"r0", "r1"}, NewAlphabet('a', 'b'), delta, []State{"r0"}, []State{"r1"})
+
+	if empty, _, _ := finitelyManyA.IsEmpty(); empty {
+		t.Fatal("'finitely many a' should not be empty")
+	}
+	if empty, u, v := IntersectBuchi(finitelyManyA, infinitelyMany('a', "q")).IsEmpty(); !empty {
+		t.Fatalf("intersection should be empty, got witness %s(%s)^ω", u, v)
+	}
+}
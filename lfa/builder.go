@@ -0,0 +1,264 @@
+package lfa
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ValidationKind int
+
+const (
+	ValidationUnknownState ValidationKind = iota
+	ValidationDuplicateState
+	ValidationMissingStart
+	ValidationMultipleStarts
+	ValidationSymbolOutsideAlphabet
+	ValidationEpsilonInDFA
+	ValidationNondeterministic
+)
+
+// ValidationError is a problem found while building an automaton. Context
+// tells which declaration it comes from, e.g. "edge q0 -a-> q1" or "final".
+type ValidationError struct {
+	Kind    ValidationKind
+	State   State
+	Symbol  RuneRange
+	Context string
+}
+
+func (e *ValidationError) Error() string {
+	switch e.Kind {
+	case ValidationUnknownState:
+		return fmt.Sprintf("%s: unknown state %s", e.Context, e.State)
+	case ValidationDuplicateState:
+		return fmt.Sprintf("%s: state %s is declared twice", e.Context, e.State)
+	case ValidationMissingStart:
+		return "no start state"
+	case ValidationMultipleStarts:
+		return fmt.Sprintf("%s: a DFA has a single start state, %s is the second one", e.Context, e.State)
+	case ValidationSymbolOutsideAlphabet:
+		return fmt.Sprintf("%s: %s is not in the alphabet", e.Context, e.Symbol)
+	case ValidationEpsilonInDFA:
+		return fmt.Sprintf("%s: a DFA has no ε-transitions", e.Context)
+	case ValidationNondeterministic:
+		return fmt.Sprintf("%s: %s already has a transition on %s", e.Context, e.State, e.Symbol)
+	}
+	return e.Context
+}
+
+// ValidationErrors lists every problem found by Build, in the order of the
+// declarations
+type ValidationErrors []*ValidationError
+
+func (errs ValidationErrors) Error() string {
+	lines := make([]string, 0, len(errs))
+	for _, e := range errs {
+		lines = append(lines, e.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// builder records the declarations shared by DFABuilder and NFABuilder
+type builder struct {
+	states   []State
+	alphabet Alphabet // nil when inferred from the edges
+	edges    []edge
+	starts   []State
+	finals   []State
+}
+
+func edgeContext(e edge) string {
+	return fmt.Sprintf("edge %s -%s-> %s", e.from, e.symbol, e.to)
+}
+
+// validate checks the declarations and returns the known states and the
+// alphabet of the automaton
+func (b *builder) validate(deterministic bool) ([]State, Alphabet, ValidationErrors) {
+	errs := make(ValidationErrors, 0)
+	known := make(map[State]bool)
+	states := make([]State, 0, len(b.states))
+	for _, q := range b.states {
+		if known[q] {
+			errs = append(errs, &ValidationError{Kind: ValidationDuplicateState, State: q, Context: "state"})
+			continue
+		}
+		known[q] = true
+		states = append(states, q)
+	}
+
+	check := func(q State, context string) {
+		if !known[q] {
+			errs = append(errs, &ValidationError{Kind: ValidationUnknownState, State: q, Context: context})
+		}
+	}
+
+	if len(b.starts) == 0 {
+		errs = append(errs, &ValidationError{Kind: ValidationMissingStart, Context: "start"})
+	}
+	for i, q := range b.starts {
+		check(q, "start")
+		if deterministic && i > 0 {
+			errs = append(errs, &ValidationError{Kind: ValidationMultipleStarts, State: q, Context: "start"})
+		}
+	}
+	for _, q := range b.finals {
+		check(q, "final")
+	}
+
+	sigma := b.alphabet
+	if sigma == nil {
+		sigma = make(Alphabet, 0)
+		for _, e := range b.edges {
+			if !e.symbol.IsEpsilon() && !contains(sigma, e.symbol) {
+				sigma = append(sigma, e.symbol)
+			}
+		}
+	}
+	universe := sigma.Normalize()
+
+	labels := make(map[State][]RuneRange)
+	for _, e := range b.edges {
+		context := edgeContext(e)
+		check(e.from, context)
+		check(e.to, context)
+
+		if e.symbol.IsEpsilon() {
+			if deterministic {
+				errs = append(errs, &ValidationError{Kind: ValidationEpsilonInDFA, State: e.from, Symbol: e.symbol, Context: context})
+			}
+			continue
+		}
+		if len(universe.Complement(Alphabet{e.symbol})) != 0 {
+			errs = append(errs, &ValidationError{Kind: ValidationSymbolOutsideAlphabet, State: e.from, Symbol: e.symbol, Context: context})
+		}
+
+		if deterministic {
+			for _, other := range labels[e.from] {
+				if overlap, ok := other.intersect(e.symbol); ok {
+					errs = append(errs, &ValidationError{Kind: ValidationNondeterministic, State: e.from, Symbol: overlap, Context: context})
+					break
+				}
+			}
+			labels[e.from] = append(labels[e.from], e.symbol)
+		}
+	}
+
+	return states, sigma, errs
+}
+
+// DFABuilder declares a DFA step by step and checks it in Build, instead of
+// accepting anything like NewDFA
+type DFABuilder struct {
+	b builder
+}
+
+// NewDFABuilder starts an empty DFA declaration
+func NewDFABuilder() *DFABuilder {
+	return &DFABuilder{}
+}
+
+// Alphabet declares Sigma. Without it, Sigma is made of the edge labels.
+func (d *DFABuilder) Alphabet(symbols ...RuneRange) *DFABuilder {
+	d.b.alphabet = append(append(Alphabet{}, d.b.alphabet...), symbols...)
+	return d
+}
+
+// State declares states
+func (d *DFABuilder) State(states ...State) *DFABuilder {
+	d.b.states = append(d.b.states, states...)
+	return d
+}
+
+// Start declares the start state
+func (d *DFABuilder) Start(state State) *DFABuilder {
+	d.b.starts = append(d.b.starts, state)
+	return d
+}
+
+// Final declares final states
+func (d *DFABuilder) Final(states ...State) *DFABuilder {
+	d.b.finals = append(d.b.finals, states...)
+	return d
+}
+
+// Edge declares the transition from one state to another on a symbol
+func (d *DFABuilder) Edge(from State, symbol RuneRange, to State) *DFABuilder {
+	d.b.edges = append(d.b.edges, edge{from, symbol, to})
+	return d
+}
+
+// Build checks the declarations and returns the DFA, or the ValidationErrors
+// found: unknown or duplicate states, a missing or second start state,
+// symbols outside the alphabet, ε-transitions and overlapping labels.
+func (d *DFABuilder) Build() (*DFA, error) {
+	states, sigma, errs := d.b.validate(true)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	delta := make(DeltaDFA)
+	for _, e := range d.b.edges {
+		delta.Add(e.from, e.symbol, e.to)
+	}
+	return NewDFA(states, sigma, delta, d.b.starts[0], append([]State{}, d.b.finals...)), nil
+}
+
+// NFABuilder declares an NFA step by step and checks it in Build, instead of
+// accepting anything like NewNFA
+type NFABuilder struct {
+	b builder
+}
+
+// NewNFABuilder starts an empty NFA declaration
+func NewNFABuilder() *NFABuilder {
+	return &NFABuilder{}
+}
+
+// Alphabet declares Sigma. Without it, Sigma is made of the edge labels.
+func (n *NFABuilder) Alphabet(symbols ...RuneRange) *NFABuilder {
+	n.b.alphabet = append(append(Alphabet{}, n.b.alphabet...), symbols...)
+	return n
+}
+
+// State declares states
+func (n *NFABuilder) State(states ...State) *NFABuilder {
+	n.b.states = append(n.b.states, states...)
+	return n
+}
+
+// Start declares start states
+func (n *NFABuilder) Start(states ...State) *NFABuilder {
+	n.b.starts = append(n.b.starts, states...)
+	return n
+}
+
+// Final declares final states
+func (n *NFABuilder) Final(states ...State) *NFABuilder {
+	n.b.finals = append(n.b.finals, states...)
+	return n
+}
+
+// Edge declares transitions from one state to each of the targets on a
+// symbol, which may be Epsilon
+func (n *NFABuilder) Edge(from State, symbol RuneRange, to ...State) *NFABuilder {
+	for _, q := range to {
+		n.b.edges = append(n.b.edges, edge{from, symbol, q})
+	}
+	return n
+}
+
+// Build checks the declarations and returns the NFA, or the ValidationErrors
+// found: unknown or duplicate states, a missing start state and symbols
+// outside the alphabet.
+func (n *NFABuilder) Build() (*NFA, error) {
+	states, sigma, errs := n.b.validate(false)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	delta := make(DeltaNfa)
+	for _, e := range n.b.edges {
+		delta.Add(e.from, e.symbol, NewSetState(e.to))
+	}
+	return NewNFA(states, sigma, delta, append([]State{}, n.b.starts...), append([]State{}, n.b.finals...)), nil
+}
@@ -0,0 +1,105 @@
+package lfa
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDFABuilder(t *testing.T) {
+	dfa, err := NewDFABuilder().
+		Alphabet(Sym('a'), Sym('b')).
+		State("q0", "q1").
+		Start("q0").
+		Final("q1").
+		Edge("q0", Sym('a'), "q1").
+		Edge("q1", Range('a', 'b'), "q1").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert(t, dfa.Accept("abba"), "should accept abba")
+	assert(t, !dfa.Accept("b"), "should reject b")
+	if got := dfa.Sigma.String(); got != "[ab]" {
+		t.Errorf("expected Sigma [ab], got %s", got)
+	}
+}
+
+func TestDFABuilderErrors(t *testing.T) {
+	_, err := NewDFABuilder().
+		Alphabet(Sym('a'), Sym('b')).
+		State("q0", "q1", "q1").
+		Start("q0").
+		Start("q1").
+		Final("q2").
+		Edge("q0", Sym('a'), "q1").
+		Edge("q0", Range('a', 'c'), "q0").
+		Edge("q1", Epsilon, "q0").
+		Edge("q3", Sym('b'), "q0").
+		Build()
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+
+	want := []struct {
+		kind  ValidationKind
+		state State
+	}{
+		{ValidationDuplicateState, "q1"},
+		{ValidationMultipleStarts, "q1"},
+		{ValidationUnknownState, "q2"},
+		{ValidationSymbolOutsideAlphabet, "q0"},
+		{ValidationNondeterministic, "q0"},
+		{ValidationEpsilonInDFA, "q1"},
+		{ValidationUnknownState, "q3"},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got:\n%v", len(want), errs)
+	}
+	for i, w := range want {
+		if errs[i].Kind != w.kind || errs[i].State != w.state {
+			t.Errorf("error %d: got %v", i, errs[i])
+		}
+	}
+	if got := errs[4].Error(); got != "edge q0 -[a-c]-> q0: q0 already has a transition on a" {
+		t.Errorf("unexpected message %q", got)
+	}
+
+	if _, err := NewDFABuilder().State("q0").Build(); err == nil || err.Error() != "no start state" {
+		t.Errorf("expected a missing start state, got %v", err)
+	}
+}
+
+func TestNFABuilder(t *testing.T) {
+	nfa, err := NewNFABuilder().
+		State("q0", "q1", "q2").
+		Start("q0").
+		Final("q2").
+		Edge("q0", Sym('a'), "q0", "q1").
+		Edge("q1", Epsilon, "q2").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, nfa.Accept("aaa"), "should accept aaa")
+	assert(t, !nfa.Accept(""), "should reject the empty word")
+	// Without a declared alphabet Sigma is made of the labels
+	if got := nfa.Sigma.String(); got != "a" {
+		t.Errorf("expected Sigma a, got %s", got)
+	}
+
+	// Unlike NewNFA, a label outside the declared alphabet is an error
+	_, err = NewNFABuilder().
+		Alphabet(Sym('a')).
+		State("q0").
+		Start("q0", "q1").
+		Edge("q0", Sym('b'), "q0").
+		Build()
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 2 ||
+		errs[0].Kind != ValidationUnknownState || errs[1].Kind != ValidationSymbolOutsideAlphabet {
+		t.Errorf("unexpected errors: %v", err)
+	}
+}
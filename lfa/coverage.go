@@ -0,0 +1,215 @@
+package lfa
+
+import "sort"
+
+// TestSuite is a set of words for testing an implementation of a language:
+// Accepted words exercise every useful transition and final state of the
+// automaton they were generated from, Rejected words are near misses that
+// differ from an accepted word by a single edit.
+type TestSuite struct {
+	Accepted []string
+	Rejected []string
+}
+
+// edge is a single transition of an NFA
+type edge struct {
+	from   State
+	symbol RuneRange
+	to     State
+}
+
+// sortedEdges lists the transitions of the NFA in a stable order
+func (n *NFA) sortedEdges() []edge {
+	edges := make([]edge, 0)
+	for from, transitions := range n.Delta {
+		for symbol, states := range transitions {
+			for to := range states {
+				edges = append(edges, edge{from, symbol, to})
+			}
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		a, b := edges[i], edges[j]
+		if a.from != b.from {
+			return a.from < b.from
+		}
+		if a.symbol != b.symbol {
+			return a.symbol.Lo < b.symbol.Lo || (a.symbol.Lo == b.symbol.Lo && a.symbol.Hi < b.symbol.Hi)
+		}
+		return a.to < b.to
+	})
+	return edges
+}
+
+// shortestPaths runs a 0-1 breadth-first search, where epsilon edges cost
+// nothing and symbols cost one, from the given states along edges (or along
+// reversed edges when backward is set). It returns for every reached state
+// the edge used to reach it; sources map to nil.
+func shortestPaths(edges []edge, sources []State, backward bool) map[State]*edge {
+	out := make(map[State][]edge)
+	for _, e := range edges {
+		if backward {
+			out[e.to] = append(out[e.to], e)
+		} else {
+			out[e.from] = append(out[e.from], e)
+		}
+	}
+
+	via := make(map[State]*edge)
+	dist := make(map[State]int)
+	deque := make([]State, 0)
+	for _, s := range sources {
+		via[s] = nil
+		dist[s] = 0
+		deque = append(deque, s)
+	}
+
+	for len(deque) > 0 {
+		q := deque[0]
+		deque = deque[1:]
+
+		for i := range out[q] {
+			e := &out[q][i]
+			next := e.to
+			if backward {
+				next = e.from
+			}
+			cost := 1
+			if e.symbol.IsEpsilon() {
+				cost = 0
+			}
+
+			if d, seen := dist[next]; seen && d <= dist[q]+cost {
+				continue
+			}
+			dist[next] = dist[q] + cost
+			via[next] = e
+			if cost == 0 {
+				deque = append([]State{next}, deque...)
+			} else {
+				deque = append(deque, next)
+			}
+		}
+	}
+
+	return via
+}
+
+// CoverageSuite generates a test suite from the automaton. Every transition
+// lying on some accepting path is used by at least one accepted word, and
+// every reachable final state ends at least one. Each transition that is not
+// yet covered contributes the word made of the shortest path to it, the
+// transition and the shortest path from it to a final state, so the suite
+// stays small. Near misses are built by deleting, replacing or inserting one
+// symbol of an accepted word, keeping the results the automaton rejects.
+func (n *NFA) CoverageSuite() TestSuite {
+	edges := n.sortedEdges()
+	toEdge := shortestPaths(edges, n.Q0, false)
+	toFinal := shortestPaths(edges, n.F, true)
+
+	covered := make(map[edge]bool)
+	accepted := make([]string, 0)
+	seen := make(map[string]bool)
+
+	// addPath builds the word of the accepting path through e (or ending in
+	// state when e is nil) and marks all of its edges as covered
+	addPath := func(state State, e *edge) {
+		path := make([]edge, 0)
+		end := state
+		if e != nil {
+			state, end = e.from, e.to
+		}
+		for at := state; toEdge[at] != nil; at = toEdge[at].from {
+			path = append([]edge{*toEdge[at]}, path...)
+		}
+		if e != nil {
+			path = append(path, *e)
+		}
+		for at := end; toFinal[at] != nil; at = toFinal[at].to {
+			path = append(path, *toFinal[at])
+		}
+
+		word := make([]rune, 0, len(path))
+		for _, p := range path {
+			covered[p] = true
+			if !p.symbol.IsEpsilon() {
+				word = append(word, p.symbol.Lo)
+			}
+		}
+		if !seen[string(word)] {
+			seen[string(word)] = true
+			accepted = append(accepted, string(word))
+		}
+	}
+
+	for i := range edges {
+		e := &edges[i]
+		_, reachable := toEdge[e.from]
+		_, productive := toFinal[e.to]
+		if reachable && productive && !covered[*e] {
+			addPath(e.from, e)
+		}
+	}
+
+	finals := append([]State{}, n.F...)
+	sort.Strings(finals)
+	for _, f := range finals {
+		if _, reachable := toEdge[f]; reachable {
+			addPath(f, nil)
+		}
+	}
+
+	return TestSuite{Accepted: accepted, Rejected: n.nearMisses(accepted)}
+}
+
+// nearMisses returns the single-edit mutations of the words that the
+// automaton rejects, at most one per word and position
+func (n *NFA) nearMisses(words []string) []string {
+	labels := append([]RuneRange{}, n.Sigma...)
+	for _, e := range n.sortedEdges() {
+		labels = append(labels, e.symbol)
+	}
+	symbols := make([]rune, 0)
+	for _, piece := range splitRanges(labels) {
+		symbols = append(symbols, piece.Lo)
+	}
+
+	rejected := make([]string, 0)
+	seen := make(map[string]bool)
+	try := func(candidates []string) {
+		for _, c := range candidates {
+			if !seen[c] && !n.Accept(c) {
+				seen[c] = true
+				rejected = append(rejected, c)
+				return
+			}
+		}
+	}
+
+	for _, w := range words {
+		runes := []rune(w)
+		for i := 0; i <= len(runes); i++ {
+			candidates := make([]string, 0)
+			if i < len(runes) {
+				candidates = append(candidates, string(runes[:i])+string(runes[i+1:]))
+				for _, c := range symbols {
+					if c != runes[i] {
+						candidates = append(candidates, string(runes[:i])+string(c)+string(runes[i+1:]))
+					}
+				}
+			}
+			for _, c := range symbols {
+				candidates = append(candidates, string(runes[:i])+string(c)+string(runes[i:]))
+			}
+			try(candidates)
+		}
+	}
+
+	return rejected
+}
+
+// CoverageSuite generates a test suite covering every transition and final
+// state of the DFA, see NFA.CoverageSuite
+func (d *DFA) CoverageSuite() TestSuite {
+	return d.ToNFA().CoverageSuite()
+}
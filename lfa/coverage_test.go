@@ -0,0 +1,100 @@
+package lfa
+
+import "testing"
+
+// editDistanceOne reports whether b is a by one deletion, substitution or
+// insertion
+func editDistanceOne(a, b []rune) bool {
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+	if len(a)-len(b) > 1 {
+		return false
+	}
+	i := 0
+	for i < len(b) && a[i] == b[i] {
+		i++
+	}
+	if len(a) == len(b) {
+		return i < len(a) && string(a[i+1:]) == string(b[i+1:])
+	}
+	return string(a[i+1:]) == string(b[i:])
+}
+
+func TestCoverageSuiteCoversDFA(t *testing.T) {
+	for _, pattern := range []string{"(a|b)*abb", "1(0|1)*2(3|4){5}36", "a[b-d]?e+|x", "()"} {
+		nfa, err := CreateNFAFromRegex(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dfa := nfa.ToDFA()
+		suite := dfa.CoverageSuite()
+
+		// Replay every accepted word and record the transitions it takes
+		used := make(map[State]map[RuneRange]bool)
+		for _, w := range suite.Accepted {
+			if !dfa.Accept(w) || !nfa.Accept(w) {
+				t.Fatalf("'%s': suite word %q is not accepted", pattern, w)
+			}
+			q := dfa.Q0
+			for _, c := range w {
+				for symbol, next := range dfa.Delta[q] {
+					if symbol.Contains(c) {
+						if used[q] == nil {
+							used[q] = make(map[RuneRange]bool)
+						}
+						used[q][symbol] = true
+						q = next
+						break
+					}
+				}
+			}
+		}
+
+		// Thompson DFAs are trim, so every transition must be used
+		for q, transitions := range dfa.Delta {
+			for symbol := range transitions {
+				if !used[q][symbol] {
+					t.Errorf("'%s': transition %s --%s--> not covered by %q", pattern, q, symbol, suite.Accepted)
+				}
+			}
+		}
+
+		for _, w := range suite.Rejected {
+			if nfa.Accept(w) {
+				t.Errorf("'%s': near miss %q is accepted", pattern, w)
+			}
+			near := false
+			for _, a := range suite.Accepted {
+				near = near || editDistanceOne([]rune(a), []rune(w))
+			}
+			if !near {
+				t.Errorf("'%s': %q is not a single edit away from an accepted word", pattern, w)
+			}
+		}
+		if len(suite.Rejected) == 0 && pattern != "()" {
+			t.Errorf("'%s': no near misses generated", pattern)
+		}
+
+		t.Logf("'%s': %d accepted, %d rejected", pattern, len(suite.Accepted), len(suite.Rejected))
+	}
+}
+
+func TestCoverageSuiteNFAFinals(t *testing.T) {
+	// Two final states reachable through epsilon moves only
+	delta := make(DeltaNfa)
+	delta.Add("q0", Epsilon, NewSetState("q1", "q2"))
+	delta.Add("q1", Sym('a'), NewSetState("q1"))
+	delta.Add("q3", Sym('b'), NewSetState("q2"))
+	nfa := NewNFA([]State{"q0", "q1", "q2", "q3"}, NewAlphabet('a', 'b'), delta, []State{"q0"}, []State{"q1", "q2"})
+
+	suite := nfa.CoverageSuite()
+	if len(suite.Accepted) != 2 || suite.Accepted[0] != "" || suite.Accepted[1] != "a" {
+		t.Errorf("expected [\"\" \"a\"], got %q", suite.Accepted)
+	}
+	for _, w := range []string{"b", "ab"} {
+		if !contains(suite.Rejected, w) {
+			t.Errorf("expected near miss %q in %q", w, suite.Rejected)
+		}
+	}
+}
@@ -1,8 +1,6 @@
 package lfa
 
-import (
-	"log"
-)
+import "fmt"
 
 type State = string
 
@@ -15,17 +13,17 @@ func contains[T comparable](list []T, item T) bool {
 	return false
 }
 
-type DeltaDFA map[State]map[byte]State
+type DeltaDFA map[State]map[RuneRange]State
 
-func (d DeltaDFA) Add(in State, r byte, out State) {
+func (d DeltaDFA) Add(in State, r RuneRange, out State) {
 	key := in
 	if _, ok := d[key]; !ok {
-		d[key] = make(map[byte]State)
+		d[key] = make(map[RuneRange]State)
 	}
 	d[key][r] = out
 }
 
-func (d DeltaDFA) Lookup(in State, r byte) State {
+func (d DeltaDFA) Lookup(in State, r RuneRange) State {
 	if q, ok := d[in]; !ok {
 		return ""
 	} else {
@@ -34,7 +32,18 @@ func (d DeltaDFA) Lookup(in State, r byte) State {
 
 }
 
-func (d DeltaDFA) LookupQ(in State) map[byte]State {
+// LookupRune returns the state reached from in by reading c, following the
+// range label that contains it
+func (d DeltaDFA) LookupRune(in State, c rune) State {
+	for r, out := range d[in] {
+		if r.Contains(c) {
+			return out
+		}
+	}
+	return ""
+}
+
+func (d DeltaDFA) LookupQ(in State) map[RuneRange]State {
 	if q, ok := d[in]; !ok {
 		return nil
 	} else {
@@ -44,13 +53,13 @@ func (d DeltaDFA) LookupQ(in State) map[byte]State {
 
 type DFA struct {
 	Q     []State
-	Sigma []byte
+	Sigma Alphabet
 	Delta DeltaDFA
 	Q0    State
 	F     []State
 }
 
-func NewDFA(q []State, sigma []byte, delta DeltaDFA, q0 State, f []State) *DFA {
+func NewDFA(q []State, sigma Alphabet, delta DeltaDFA, q0 State, f []State) *DFA {
 	dfa := &DFA{
 		Q:     q,
 		Sigma: sigma,
@@ -65,10 +74,8 @@ func NewDFA(q []State, sigma []byte, delta DeltaDFA, q0 State, f []State) *DFA {
 func (d *DFA) Accept(s string) bool {
 	q := d.Q0
 
-	for _, char := range s {
-		symbol := byte(char)
-
-		if !contains(d.Sigma, symbol) {
+	for _, symbol := range s {
+		if !d.Sigma.Contains(symbol) {
 			// log.Printf("Symbol %v not in Sigma", symbol)
 			return false
 		}
@@ -78,9 +85,8 @@ func (d *DFA) Accept(s string) bool {
 			return false
 		}
 
-		q = d.Delta.Lookup(q, symbol)
+		q = d.Delta.LookupRune(q, symbol)
 		if len(q) == 0 {
-			log.Printf("q = %v, r = %v", q, symbol)
 			return false
 		}
 	}
@@ -88,17 +94,37 @@ func (d *DFA) Accept(s string) bool {
 	return contains(d.F, q)
 }
 
+// ToNFA returns the DFA as an NFA with the same states and transitions
+func (d *DFA) ToNFA() *NFA {
+	delta := make(DeltaNfa)
+	for state, transitions := range d.Delta {
+		for symbol, next := range transitions {
+			delta.Add(state, symbol, NewSetState(next))
+		}
+	}
+
+	return NewNFA(
+		append([]State{}, d.Q...),
+		append(Alphabet{}, d.Sigma...),
+		delta,
+		[]State{d.Q0},
+		append([]State{}, d.F...),
+	)
+}
+
 func (d *DFA) ToGrammar() *Grammar {
 	grammar := &Grammar{
 		Vn: d.Q,
-		Vt: d.Sigma,
+		Vt: d.Sigma.Runes(),
 		P:  make(map[State][]string),
 		S:  d.Q0,
 	}
 
 	for state, transitions := range d.Delta {
 		for symbol, nextState := range transitions {
-			grammar.P[state] = append(grammar.P[state], string(symbol)+nextState)
+			for c := symbol.Lo; c <= symbol.Hi; c++ {
+				grammar.P[state] = append(grammar.P[state], string(c)+nextState)
+			}
 		}
 	}
 
@@ -108,3 +134,108 @@ func (d *DFA) ToGrammar() *Grammar {
 
 	return grammar
 }
+
+// IntersectDFA builds the product automaton accepting the words accepted by
+// both a and b. States are pairs "(p,q)" reachable from (a.Q0,b.Q0) and every
+// edge is labelled with the intersection of the labels of a and b, so the
+// product stays deterministic.
+func IntersectDFA(a, b *DFA) *DFA {
+	name := func(p, q State) State {
+		return fmt.Sprintf("(%s,%s)", p, q)
+	}
+
+	type pair struct{ p, q State }
+	start := pair{a.Q0, b.Q0}
+	states := []State{name(a.Q0, b.Q0)}
+	final := make([]State, 0)
+	delta := make(DeltaDFA)
+	seen := map[pair]bool{start: true}
+	queue := []pair{start}
+
+	for len(queue) > 0 {
+		t := queue[0]
+		queue = queue[1:]
+		from := name(t.p, t.q)
+		if contains(a.F, t.p) && contains(b.F, t.q) {
+			final = append(final, from)
+		}
+
+		for ra, p := range a.Delta[t.p] {
+			for rb, q := range b.Delta[t.q] {
+				symbol, ok := ra.intersect(rb)
+				if !ok {
+					continue
+				}
+				next := pair{p, q}
+				if !seen[next] {
+					seen[next] = true
+					states = append(states, name(p, q))
+					queue = append(queue, next)
+				}
+				delta.Add(from, symbol, name(p, q))
+			}
+		}
+	}
+
+	sigma := make(Alphabet, 0)
+	for _, ra := range a.Sigma {
+		for _, rb := range b.Sigma {
+			if r, ok := ra.intersect(rb); ok {
+				sigma = append(sigma, r)
+			}
+		}
+	}
+
+	return NewDFA(states, sigma, delta, name(a.Q0, b.Q0), final)
+}
+
+// Complement builds a DFA accepting exactly the words over universe that d
+// rejects. The alphabet is refined so that every symbol lies either inside
+// one label of d or outside of them, the automaton is completed with a sink
+// state "{}" and final and non-final states are swapped.
+func (d *DFA) Complement(universe Alphabet) *DFA {
+	universe = universe.Normalize()
+
+	sigma := make(Alphabet, 0)
+	for _, piece := range splitRanges(append(append(d.labels(), d.Sigma...), universe...)) {
+		if universe.Contains(piece.Lo) {
+			sigma = append(sigma, piece)
+		}
+	}
+
+	const sink = "{}"
+	states := append([]State{}, d.Q...)
+	delta := make(DeltaDFA)
+	usesSink := false
+
+	for _, state := range d.Q {
+		for _, symbol := range sigma {
+			next := d.Delta.LookupRune(state, symbol.Lo)
+			if next == "" {
+				next, usesSink = sink, true
+			}
+			delta.Add(state, symbol, next)
+		}
+	}
+
+	if usesSink || len(d.Q) == 0 {
+		states = append(states, sink)
+		for _, symbol := range sigma {
+			delta.Add(sink, symbol, sink)
+		}
+	}
+
+	final := make([]State, 0)
+	for _, state := range states {
+		if !contains(d.F, state) {
+			final = append(final, state)
+		}
+	}
+
+	q0 := d.Q0
+	if len(d.Q) == 0 {
+		q0 = sink
+	}
+
+	return NewDFA(states, sigma, delta, q0, final)
+}
@@ -25,10 +25,14 @@ func (d *DFA) ToDOT(filename string) error {
 	fmt.Fprintf(file, "  \"\" [shape=none];\n")
 	fmt.Fprintf(file, "  \"\" -> \"%s\";\n", d.Q0)
 
-	// Add transitions
+	// Add transitions, one edge per state pair labeled with all its ranges
 	for state, trans := range d.Delta {
+		edges := make(map[State][]RuneRange)
 		for symbol, nextState := range trans {
-			fmt.Fprintf(file, "  \"%s\" -> \"%s\" [label=\"%c\"];\n", state, nextState, symbol)
+			edges[nextState] = append(edges[nextState], symbol)
+		}
+		for nextState, symbols := range edges {
+			fmt.Fprintf(file, "  \"%s\" -> \"%s\" [label=\"%s\"];\n", state, nextState, formatLabel(symbols))
 		}
 	}
 
@@ -58,17 +62,22 @@ func (n *NFA) ToDOT(filename string) error {
 		fmt.Fprintf(file, "  \"\" -> \"%s\";\n", q0)
 	}
 
-	// Add transitions
+	// Add transitions, one edge per state pair labeled with all its ranges;
+	// epsilon moves keep their own edge
 	for state, transitions := range n.Delta {
+		edges := make(map[State][]RuneRange)
 		for symbol, nextStates := range transitions {
 			for nextState := range nextStates {
-				label := string(symbol)
-				if symbol == Epsilon {
-					label = "ε"
+				if symbol.IsEpsilon() {
+					fmt.Fprintf(file, "  \"%s\" -> \"%s\" [label=\"%s\"];\n", state, nextState, Epsilon)
+					continue
 				}
-				fmt.Fprintf(file, "  \"%s\" -> \"%s\" [label=\"%s\"];\n", state, nextState, label)
+				edges[nextState] = append(edges[nextState], symbol)
 			}
 		}
+		for nextState, symbols := range edges {
+			fmt.Fprintf(file, "  \"%s\" -> \"%s\" [label=\"%s\"];\n", state, nextState, formatLabel(symbols))
+		}
 	}
 
 	fmt.Fprintln(file, "}")
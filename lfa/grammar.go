@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/rand"
 	"strings"
+	"unicode/utf8"
 )
 
 var (
@@ -15,7 +16,7 @@ type NonTerminal = string
 type Grammar struct {
 	S  NonTerminal
 	Vn []NonTerminal
-	Vt []byte
+	Vt []rune
 	P  map[NonTerminal][]string
 }
 
@@ -23,7 +24,7 @@ func NewGrammarV5() *Grammar {
 	return &Grammar{
 		S:  "S",
 		Vn: []NonTerminal{"S", "F", "L"},
-		Vt: []byte{'a', 'b', 'c', 'd'},
+		Vt: []rune{'a', 'b', 'c', 'd'},
 		P: map[NonTerminal][]string{
 			"S": {"bS", "aF", "d"},
 			"F": {"cF", "dF", "aL", "b"},
@@ -36,7 +37,7 @@ func NewGrammarV51() *Grammar {
 	return &Grammar{
 		S:  "S",
 		Vn: []NonTerminal{"S", "F", "L"},
-		Vt: []byte{'a', 'b', 'c', 'd'},
+		Vt: []rune{'a', 'b', 'c', 'd'},
 		P: map[NonTerminal][]string{
 			"S": {"Sb", "aF", "d"},
 			"F": {"cF", "dF", "aL", "b"},
@@ -53,13 +54,12 @@ func (g *Grammar) getRandomWord() string {
 		sufArr := g.P[vn]
 		suf := sufArr[rand.Intn(len(sufArr))]
 
-		if len(suf) == 1 {
-			word += string(suf[0])
+		c, size := utf8.DecodeRuneInString(suf)
+		word += string(c)
+		if size == len(suf) {
 			return word
 		}
-
-		word += string(suf[0])
-		vn = NonTerminal(suf[1])
+		vn = NonTerminal(suf[size:])
 	}
 }
 
@@ -86,20 +86,21 @@ func (g *Grammar) ToDFA() *DFA {
 	}
 
 	for k, v := range g.P {
-		delta[k] = make(map[byte]State)
+		delta[k] = make(map[RuneRange]State)
 
 		for _, s := range v {
-			terminal := s[0]
-			if len(s) == 1 {
+			c, size := utf8.DecodeRuneInString(s)
+			terminal := Sym(c)
+			if size == len(s) {
 				delta[k][terminal] = finalState
 				continue
 			}
 
-			delta[k][terminal] = NonTerminal(s[1])
+			delta[k][terminal] = NonTerminal(s[size:])
 		}
 	}
 
-	dfa := NewDFA(q, g.Vt, delta, g.S, []State{finalState})
+	dfa := NewDFA(q, NewAlphabet(g.Vt...), delta, g.S, []State{finalState})
 	return dfa
 }
 
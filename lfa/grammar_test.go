@@ -20,3 +20,66 @@ func TestRandomWordsAreUnique(t *testing.T) {
 
 	t.Logf("Generated successfully %v unique words", len(randWords))
 }
+
+func TestGrammarNonASCIITerminals(t *testing.T) {
+	g := &Grammar{
+		S:  "S",
+		Vn: []NonTerminal{"S", "F"},
+		Vt: []rune{'é', 'ß'},
+		P: map[NonTerminal][]string{
+			"S": {"éF"},
+			"F": {"ßF", "é"},
+		},
+	}
+	if got := g.ClassifyGrammar(); got != "Type 3: Regular Grammar" {
+		t.Errorf("expected a regular grammar, got %s", got)
+	}
+
+	d := g.ToDFA()
+	for _, w := range []string{"éé", "éßßé"} {
+		assert(t, d.Accept(w), "should accept "+w)
+	}
+	assert(t, !d.Accept("ßé"), "should reject ßé")
+	if w := g.getRandomWord(); !d.Accept(w) {
+		t.Errorf("random word %q is rejected", w)
+	}
+
+	// The productions of DFA.ToGrammar read back as the same edges
+	back := d.ToGrammar().ToDFA()
+	if got := back.Delta.LookupRune("S", 'é'); got != "F" {
+		t.Errorf("expected S -é-> F after the round trip, got %q", got)
+	}
+	if got := back.Delta.LookupRune("F", 'ß'); got != "F" {
+		t.Errorf("expected F -ß-> F after the round trip, got %q", got)
+	}
+}
+
+func TestClassifyGrammar(t *testing.T) {
+	cases := []struct {
+		grammar *Grammar
+		want    string
+	}{
+		{NewGrammarV5(), "Type 3: Regular Grammar"},
+		// Right- and left-linear rules mixed
+		{NewGrammarV51(), "Type 2: Context-Free Grammar"},
+		// Multi-character non-terminals, as produced by DFA.ToGrammar
+		{&Grammar{S: "q0", Vn: []NonTerminal{"q0", "q1"}, Vt: []rune{'a'}, P: map[NonTerminal][]string{
+			"q0": {"aq1"}, "q1": {"aq0", "a"},
+		}}, "Type 3: Regular Grammar"},
+		// aS → Sa keeps the length but rewrites its context
+		{&Grammar{S: "S", Vn: []NonTerminal{"S"}, Vt: []rune{'a'}, P: map[NonTerminal][]string{
+			"S": {"aS", "a"}, "aS": {"Sa"},
+		}}, "Type 1: Noncontracting Grammar"},
+		{&Grammar{S: "S", Vn: []NonTerminal{"S", "A"}, Vt: []rune{'a', 'b'}, P: map[NonTerminal][]string{
+			"S": {"aAb"}, "aAb": {"aabb"},
+		}}, "Type 1: Context-Sensitive Grammar"},
+		{&Grammar{S: "S", Vn: []NonTerminal{"S", "A"}, Vt: []rune{'a'}, P: map[NonTerminal][]string{
+			"S": {"aA"}, "aA": {"a"},
+		}}, "Type 0: Unrestricted Grammar"},
+	}
+	for i, c := range cases {
+		if got := c.grammar.ClassifyGrammar(); got != c.want {
+			t.Errorf("grammar %d: expected %s, got %s", i, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package lfa
+
+import "sort"
+
+// inclusionNode is a pair of a state of the included automaton and the set
+// of states the including automaton can be in after reading the same word
+type inclusionNode struct {
+	p       State
+	set     setState
+	parent  *inclusionNode
+	symbol  rune
+	removed bool
+}
+
+// word rebuilds the word leading to the node
+func (n *inclusionNode) word() string {
+	runes := make([]rune, 0)
+	for at := n; at.parent != nil; at = at.parent {
+		runes = append([]rune{at.symbol}, runes...)
+	}
+	return string(runes)
+}
+
+// sorted lists the states of the set in order
+func (s setState) sorted() []State {
+	states := make([]State, 0, len(s))
+	for q := range s {
+		states = append(states, q)
+	}
+	sort.Strings(states)
+	return states
+}
+
+// subsetOf reports whether every state of s is in other
+func (s setState) subsetOf(other setState) bool {
+	if len(s) > len(other) {
+		return false
+	}
+	for state := range s {
+		if !other[state] {
+			return false
+		}
+	}
+	return true
+}
+
+// Includes reports whether every word accepted by a is accepted by b, and
+// otherwise returns a word accepted by a but not by b. It explores
+// pairs (p, S) of a state of a and the set of states of b reached by the
+// same word, without determinizing either automaton. A pair (p, S) is
+// dominated by (p, T) when T ⊆ S, since whatever b accepts from T it also
+// accepts from S: only the minimal pairs, an antichain, are explored.
+func Includes(a, b *NFA) (bool, string) {
+	labels := make([]RuneRange, 0)
+	for _, n := range []*NFA{a, b} {
+		for _, transitions := range n.Delta {
+			for symbol := range transitions {
+				labels = append(labels, symbol)
+			}
+		}
+	}
+	pieces := splitRanges(labels)
+
+	antichain := make(map[State][]*inclusionNode)
+	queue := make([]*inclusionNode, 0)
+
+	// add inserts the node unless it is dominated, and removes the nodes it
+	// dominates
+	add := func(node *inclusionNode) {
+		kept := make([]*inclusionNode, 0, len(antichain[node.p]))
+		for _, other := range antichain[node.p] {
+			if other.set.subsetOf(node.set) {
+				return
+			}
+		}
+		for _, other := range antichain[node.p] {
+			if node.set.subsetOf(other.set) {
+				other.removed = true
+			} else {
+				kept = append(kept, other)
+			}
+		}
+		antichain[node.p] = append(kept, node)
+		queue = append(queue, node)
+	}
+
+	start := b.EpsilonClosureSet(NewSetState(b.Q0...))
+	for _, p := range a.EpsilonClosureSet(NewSetState(a.Q0...)).sorted() {
+		add(&inclusionNode{p: p, set: start})
+	}
+
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		if node.removed {
+			continue
+		}
+
+		if contains(a.F, node.p) && !intersects(node.set, b.F) {
+			return false, node.word()
+		}
+
+		for _, piece := range pieces {
+			next := a.Delta.LookupRune(node.p, piece.Lo)
+			if len(next) == 0 {
+				continue
+			}
+
+			set := make(setState)
+			for q := range node.set {
+				set.Union(b.Delta.LookupRune(q, piece.Lo))
+			}
+			set = b.EpsilonClosureSet(set)
+
+			for _, p := range a.EpsilonClosureSet(next).sorted() {
+				add(&inclusionNode{p: p, set: set, parent: node, symbol: piece.Lo})
+			}
+		}
+	}
+
+	return true, ""
+}
+
+// intersects reports whether any of the states is in the set
+func intersects(set setState, states []State) bool {
+	for _, q := range states {
+		if set[q] {
+			return true
+		}
+	}
+	return false
+}
+
+// EquivalentNFA reports whether a and b accept the same language, checking
+// inclusion both ways with Includes. When they differ, the returned word is
+// accepted by exactly one of them.
+func EquivalentNFA(a, b *NFA) (bool, string) {
+	if ok, word := Includes(a, b); !ok {
+		return false, word
+	}
+	return Includes(b, a)
+}
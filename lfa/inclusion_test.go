@@ -0,0 +1,97 @@
+package lfa
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIncludes(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		included bool
+	}{
+		{"a*", "(a|b)*", true},
+		{"(a|b)*", "a*", false},
+		{"(ab)*", "(a|b)*b|", true},
+		{"a(b|c)", "ab|ac", true},
+		{"a+b", "a*b?", true},
+		{"a{2,4}", "a{3,}|aa", true},
+		{"a{2,5}", "a{3,}|aa|aaa", true},
+		{"(a|b)*a(a|b)", "(a|b)*a(a|b)(a|b)", false},
+		{"[]", "a", true},
+		{"", "a", false},
+		{"b*", "(a|b)*a|b+", false},
+	}
+
+	for _, tt := range tests {
+		a, err := CreateNFAFromRegex(tt.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := CreateNFAFromRegex(tt.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		included, word := Includes(a, b)
+		if included != tt.included {
+			t.Errorf("Includes('%s', '%s') = %v, expected %v", tt.a, tt.b, included, tt.included)
+			continue
+		}
+		if included {
+			if word != "" {
+				t.Errorf("Includes('%s', '%s') gave a counterexample %q", tt.a, tt.b, word)
+			}
+			for _, w := range allWords("abc", 6) {
+				if a.Accept(w) && !b.Accept(w) {
+					t.Fatalf("'%s' is not included in '%s': %q", tt.a, tt.b, w)
+				}
+			}
+		} else if !a.Accept(word) || b.Accept(word) {
+			t.Errorf("%q is not a counterexample to '%s' ⊆ '%s'", word, tt.a, tt.b)
+		}
+	}
+}
+
+func TestIncludesWithoutDeterminizing(t *testing.T) {
+	// The DFA of this pattern has 2^21 states, but b only ever has one
+	a, _ := CreateNFAFromRegex("(a|b)*a(a|b){20}")
+	b, _ := CreateNFAFromRegex("(a|b)*")
+	included, _ := Includes(a, b)
+	assert(t, included, "everything over {a, b} is in (a|b)*")
+
+	c, _ := CreateNFAFromRegex("(a|b)*b")
+	included, word := Includes(a, c)
+	assert(t, !included, "a(a|b){20} can end with a")
+	if !a.Accept(word) || c.Accept(word) || !strings.HasSuffix(word, "a") {
+		t.Errorf("bad counterexample %q", word)
+	}
+}
+
+func TestEquivalentNFA(t *testing.T) {
+	tests := []struct {
+		a, b       string
+		equivalent bool
+	}{
+		{"(a|b)*", "(a*b*)*", true},
+		{"a(ba)*", "(ab)*a", true},
+		{"(a|b)*abb", "(a|b)*abb|abb", true},
+		{"a{2,}", "aaa*", true},
+		{"a*", "a+", false},
+		{"ab|ba", "ab", false},
+	}
+
+	for _, tt := range tests {
+		a, _ := CreateNFAFromRegex(tt.a)
+		b, _ := CreateNFAFromRegex(tt.b)
+
+		equivalent, word := EquivalentNFA(a, b)
+		if equivalent != tt.equivalent {
+			t.Errorf("EquivalentNFA('%s', '%s') = %v, expected %v", tt.a, tt.b, equivalent, tt.equivalent)
+			continue
+		}
+		if !equivalent && a.Accept(word) == b.Accept(word) {
+			t.Errorf("%q does not tell '%s' from '%s'", word, tt.a, tt.b)
+		}
+	}
+}
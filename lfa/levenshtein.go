@@ -0,0 +1,107 @@
+package lfa
+
+import (
+	"fmt"
+	"sort"
+)
+
+// LevenshteinNFA builds an NFA accepting the words over alphabet within edit
+// distance k of word, counting insertions, deletions and substitutions of one
+// rune. State "(i,e)" means that the first i runes of word have been read
+// with e edits: a rune of word moves to (i+1,e), any other rune substitutes
+// it in (i+1,e+1), any rune can be inserted in (i,e+1) and a rune of word can
+// be deleted with an epsilon move to (i+1,e+1). The states (len(word),e) are
+// final.
+func LevenshteinNFA(word string, k int, alphabet Alphabet) *NFA {
+	return levenshteinNFA(word, k, alphabet, false)
+}
+
+// DamerauLevenshteinNFA is LevenshteinNFA where swapping two adjacent runes
+// also counts as a single edit. A transposition of runes i and i+1 goes
+// through the extra state "(i,e)ᵗ", reached by reading rune i+1 of word.
+func DamerauLevenshteinNFA(word string, k int, alphabet Alphabet) *NFA {
+	return levenshteinNFA(word, k, alphabet, true)
+}
+
+func levenshteinNFA(word string, k int, alphabet Alphabet, transpositions bool) *NFA {
+	runes := []rune(word)
+	alphabet = alphabet.Normalize()
+	name := func(i, e int) State {
+		return fmt.Sprintf("(%d,%d)", i, e)
+	}
+
+	states := make([]State, 0)
+	final := make([]State, 0)
+	delta := make(DeltaNfa)
+
+	for e := 0; e <= k; e++ {
+		for i := 0; i <= len(runes); i++ {
+			from := name(i, e)
+			states = append(states, from)
+			if i == len(runes) {
+				final = append(final, from)
+			}
+
+			if i < len(runes) {
+				delta.Add(from, Sym(runes[i]), NewSetState(name(i+1, e)))
+			}
+			if e == k {
+				continue
+			}
+
+			for _, symbol := range alphabet {
+				delta.Add(from, symbol, NewSetState(name(i, e+1)))
+			}
+			if i == len(runes) {
+				continue
+			}
+			for _, symbol := range (Alphabet{Sym(runes[i])}).Complement(alphabet) {
+				delta.Add(from, symbol, NewSetState(name(i+1, e+1)))
+			}
+			delta.Add(from, Epsilon, NewSetState(name(i+1, e+1)))
+
+			if transpositions && i+1 < len(runes) && runes[i] != runes[i+1] {
+				swap := from + "ᵗ"
+				states = append(states, swap)
+				delta.Add(from, Sym(runes[i+1]), NewSetState(swap))
+				delta.Add(swap, Sym(runes[i]), NewSetState(name(i+2, e+1)))
+			}
+		}
+	}
+
+	return NewNFA(states, append(Alphabet{}, alphabet...), delta, []State{name(0, 0)}, final)
+}
+
+// NearMatches returns the words accepted by both automata, in increasing
+// order of length and then lexicographically. It is meant for a Levenshtein
+// automaton and a language, such as a generated word list, and lists the
+// words of the language close to the Levenshtein word. The intersection must
+// be finite, which is always the case with a Levenshtein automaton.
+func NearMatches(levenshtein, language *NFA) []string {
+	product := IntersectDFA(levenshtein.ToDFA(), language.ToDFA())
+
+	words := make([]string, 0)
+	seen := make(map[string]bool)
+	var walk func(q State, prefix []rune)
+	walk = func(q State, prefix []rune) {
+		if contains(product.F, q) && !seen[string(prefix)] {
+			seen[string(prefix)] = true
+			words = append(words, string(prefix))
+		}
+		for symbol, next := range product.Delta[q] {
+			for c := symbol.Lo; c <= symbol.Hi; c++ {
+				walk(next, append(prefix, c))
+			}
+		}
+	}
+	walk(product.Q0, nil)
+
+	sort.Slice(words, func(i, j int) bool {
+		a, b := []rune(words[i]), []rune(words[j])
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return words[i] < words[j]
+	})
+	return words
+}
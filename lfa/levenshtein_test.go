@@ -0,0 +1,99 @@
+package lfa
+
+import (
+	"reflect"
+	"testing"
+)
+
+// editDistance computes the (optimal string alignment) edit distance with
+// the textbook dynamic program
+func editDistance(a, b string, transpositions bool) int {
+	x, y := []rune(a), []rune(b)
+	d := make([][]int, len(x)+1)
+	for i := range d {
+		d[i] = make([]int, len(y)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(x); i++ {
+		for j := 1; j <= len(y); j++ {
+			cost := 1
+			if x[i-1] == y[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if transpositions && i > 1 && j > 1 && x[i-1] == y[j-2] && x[i-2] == y[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(x)][len(y)]
+}
+
+func TestLevenshteinNFA(t *testing.T) {
+	alphabet := NewAlphabet('a', 'b', 'c')
+	tests := []struct {
+		word string
+		k    int
+	}{
+		{"abc", 1},
+		{"abca", 2},
+		{"", 2},
+		{"aa", 0},
+		{"ab", 3},
+	}
+
+	for _, tt := range tests {
+		for _, transpositions := range []bool{false, true} {
+			nfa := LevenshteinNFA(tt.word, tt.k, alphabet)
+			if transpositions {
+				nfa = DamerauLevenshteinNFA(tt.word, tt.k, alphabet)
+			}
+			dfa := nfa.ToDFA()
+
+			for _, w := range allWords("abc", len(tt.word)+tt.k+1) {
+				want := editDistance(tt.word, w, transpositions) <= tt.k
+				if got := nfa.Accept(w); got != want {
+					t.Fatalf("'%s' within %d (transpositions %v) on %q = %v, expected %v", tt.word, tt.k, transpositions, w, got, want)
+				}
+				if got := dfa.Accept(w); got != want {
+					t.Fatalf("DFA of '%s' within %d on %q = %v, expected %v", tt.word, tt.k, w, got, want)
+				}
+			}
+		}
+	}
+
+	// Transpositions are cheaper than two substitutions
+	assert(t, !LevenshteinNFA("abc", 1, alphabet).Accept("bac"), "bac is 2 edits from abc")
+	assert(t, DamerauLevenshteinNFA("abc", 1, alphabet).Accept("bac"), "bac is 1 transposition from abc")
+}
+
+func TestNearMatches(t *testing.T) {
+	language, err := CreateNFAFromRegex("(ca|co)(t|rt|ts)|dog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	alphabet := Alphabet{Range('a', 'z')}
+
+	got := NearMatches(LevenshteinNFA("cat", 1, alphabet), language)
+	want := []string{"cat", "cot", "cart", "cats"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NearMatches(cat, 1) = %v, expected %v", got, want)
+	}
+
+	got = NearMatches(DamerauLevenshteinNFA("cta", 1, alphabet), language)
+	if !reflect.DeepEqual(got, []string{"cat"}) {
+		t.Errorf("NearMatches(cta, 1) with transpositions = %v, expected [cat]", got)
+	}
+
+	// An infinite language still gives finitely many near matches
+	language, _ = CreateNFAFromRegex("a*b")
+	got = NearMatches(LevenshteinNFA("aab", 1, NewAlphabet('a', 'b')), language)
+	want = []string{"ab", "aab", "aaab"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NearMatches(aab, 1) = %v, expected %v", got, want)
+	}
+}
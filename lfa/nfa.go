@@ -6,14 +6,11 @@ import (
 	"strings"
 )
 
-// Epsilon represents an epsilon (ε) transition in an NFA
-const Epsilon = byte(255)
+type DeltaNfa map[State]map[RuneRange]setState
 
-type DeltaNfa map[State]map[byte]setState
-
-func (d DeltaNfa) Add(in State, r byte, out setState) {
+func (d DeltaNfa) Add(in State, r RuneRange, out setState) {
 	if _, ok := d[in]; !ok {
-		d[in] = make(map[byte]setState)
+		d[in] = make(map[RuneRange]setState)
 	}
 
 	if existing, ok := d[in][r]; ok {
@@ -27,7 +24,7 @@ func (d DeltaNfa) Add(in State, r byte, out setState) {
 	}
 }
 
-func (d DeltaNfa) Lookup(in State, r byte) setState {
+func (d DeltaNfa) Lookup(in State, r RuneRange) setState {
 	if q, ok := d[in]; !ok {
 		return nil
 	} else {
@@ -35,6 +32,21 @@ func (d DeltaNfa) Lookup(in State, r byte) setState {
 	}
 }
 
+// LookupRune returns the states reachable from in by reading c, i.e. the
+// union of the targets of every range label that contains c
+func (d DeltaNfa) LookupRune(in State, c rune) setState {
+	var out setState
+	for r, states := range d[in] {
+		if r.Contains(c) {
+			if out == nil {
+				out = make(setState)
+			}
+			out.Union(states)
+		}
+	}
+	return out
+}
+
 type setState map[string]bool
 
 func NewSetState(states ...State) setState {
@@ -82,24 +94,19 @@ func (s setState) Equals(s2 setState) bool {
 
 type NFA struct {
 	Q     []State
-	Sigma []byte
+	Sigma Alphabet
 	Delta DeltaNfa
 	Q0    []State
 	F     []State
 }
 
-func NewNFA(Q []State, Sigma []byte, Delta DeltaNfa, Q0 []State, F []State) *NFA {
-	// Ensure that all symbols used in Delta are in Sigma
-	symbolSet := make(map[byte]bool)
-	for _, symbol := range Sigma {
-		symbolSet[symbol] = true
-	}
-
+func NewNFA(Q []State, Sigma Alphabet, Delta DeltaNfa, Q0 []State, F []State) *NFA {
+	// Ensure that all symbols used in Delta are in Sigma, adding only the
+	// runes of a label that Sigma does not already cover
 	for _, transitions := range Delta {
 		for symbol := range transitions {
-			if !symbolSet[symbol] && symbol != Epsilon {
-				Sigma = append(Sigma, symbol)
-				symbolSet[symbol] = true
+			if symbol != Epsilon {
+				Sigma = append(Sigma, Sigma.Complement(Alphabet{symbol})...)
 			}
 		}
 	}
@@ -115,35 +122,11 @@ func NewNFA(Q []State, Sigma []byte, Delta DeltaNfa, Q0 []State, F []State) *NFA
 	return nfa
 }
 
+// IsDFA reports whether the NFA is a complete DFA: a single target per
+// label, no epsilon moves, no overlapping labels and a transition on every
+// symbol of Sigma from every state. CheckDFA tells what is missing.
 func (n *NFA) IsDFA() bool {
-	for _, transitions := range n.Delta {
-		symbolsSeen := make(map[byte]bool)
-
-		for symbol, destinations := range transitions {
-			if symbolsSeen[symbol] {
-				return false
-			}
-			symbolsSeen[symbol] = true
-
-			if len(destinations) != 1 {
-				return false
-			}
-		}
-
-		for _, symbol := range n.Sigma {
-			if !symbolsSeen[symbol] {
-				return false
-			}
-		}
-	}
-
-	for _, state := range n.Q {
-		if _, exists := n.Delta[state]; !exists {
-			return false
-		}
-	}
-
-	return true
+	return len(n.CheckDFA()) == 0
 }
 
 // EpsilonClosure computes the set of states reachable from a state through epsilon transitions
@@ -190,11 +173,9 @@ func (n *NFA) Accept(s string) bool {
 	currentStates = n.EpsilonClosureSet(currentStates)
 
 	for _, r := range s {
-		symbol := byte(r)
-
 		nextStates := make(setState)
 		for state := range currentStates {
-			if reachable := n.Delta.Lookup(state, symbol); reachable != nil {
+			if reachable := n.Delta.LookupRune(state, r); reachable != nil {
 				nextStates.Union(reachable)
 			}
 		}
@@ -239,12 +220,15 @@ func (n *NFA) ToDFA() *DFA {
 		}
 	}
 
-	dfa_sigma := make([]byte, 0)
-	for _, symbol := range n.Sigma {
-		if symbol != Epsilon {
-			dfa_sigma = append(dfa_sigma, symbol)
+	// Split the (possibly overlapping) range labels into disjoint pieces so
+	// that every piece behaves like a single symbol of the DFA alphabet
+	labels := make([]RuneRange, 0)
+	for _, transitions := range n.Delta {
+		for symbol := range transitions {
+			labels = append(labels, symbol)
 		}
 	}
+	dfa_sigma := Alphabet(splitRanges(labels))
 
 	for !nfaQueue.done() {
 		currentSetState := nfaQueue.dequeue()
@@ -253,7 +237,7 @@ func (n *NFA) ToDFA() *DFA {
 			out := make(setState)
 
 			for state := range currentSetState {
-				if nextStates := n.Delta.Lookup(state, r); nextStates != nil {
+				if nextStates := n.Delta.LookupRune(state, r.Lo); nextStates != nil {
 					out.Union(nextStates)
 				}
 			}
@@ -280,7 +264,9 @@ func (n *NFA) ToDFA() *DFA {
 		}
 	}
 
-	return NewDFA(qPrime, dfa_sigma, deltaPrime, q0.toState(), fPrime)
+	// The pieces only label the transitions: symbols of Sigma without any
+	// transition still belong to the DFA alphabet
+	return NewDFA(qPrime, append(Alphabet{}, n.Sigma...), deltaPrime, q0.toState(), fPrime)
 }
 
 type nfaQueue struct {
@@ -322,10 +308,13 @@ func (n nfaQueue) done() bool {
 	// }
 }
 
+// ToGrammar builds a right-linear grammar from the NFA. Range labels are
+// expanded into one production per rune, so it is only practical for small
+// alphabets.
 func (n *NFA) ToGrammar() *Grammar {
 	grammar := &Grammar{
 		Vn: n.Q,
-		Vt: n.Sigma,
+		Vt: n.Sigma.Runes(),
 		P:  make(map[State][]string),
 		S:  n.Q0[0],
 	}
@@ -333,7 +322,13 @@ func (n *NFA) ToGrammar() *Grammar {
 	for state, transitions := range n.Delta {
 		for symbol, nextStates := range transitions {
 			for nextState := range nextStates {
-				grammar.P[state] = append(grammar.P[state], string(symbol)+nextState)
+				if symbol.IsEpsilon() {
+					grammar.P[state] = append(grammar.P[state], nextState)
+					continue
+				}
+				for c := symbol.Lo; c <= symbol.Hi; c++ {
+					grammar.P[state] = append(grammar.P[state], string(c)+nextState)
+				}
 			}
 		}
 	}
@@ -346,18 +341,18 @@ func (n *NFA) ToGrammar() *Grammar {
 }
 
 // CreateBasicNFA creates a basic NFA that accepts a single character
-func CreateBasicNFA(symbol byte, statePrefix string, counter *int) *NFA {
+func CreateBasicNFA(symbol rune, statePrefix string, counter *int) *NFA {
 	start := fmt.Sprintf("%s%d", statePrefix, *counter)
 	*counter++
 	accept := fmt.Sprintf("%s%d", statePrefix, *counter)
 	*counter++
 
 	delta := make(DeltaNfa)
-	delta.Add(start, symbol, NewSetState(accept))
+	delta.Add(start, Sym(symbol), NewSetState(accept))
 
 	return NewNFA(
 		[]State{start, accept},
-		[]byte{symbol},
+		Alphabet{Sym(symbol)},
 		delta,
 		[]State{start},
 		[]State{accept},
@@ -371,7 +366,7 @@ func CreateEmptyNFA(statePrefix string, counter *int) *NFA {
 
 	return NewNFA(
 		[]State{state},
-		[]byte{},
+		Alphabet{},
 		make(DeltaNfa),
 		[]State{state},
 		[]State{state},
@@ -379,7 +374,7 @@ func CreateEmptyNFA(statePrefix string, counter *int) *NFA {
 }
 
 // CreateWildcardNFA creates an NFA that accepts any single character from the alphabet
-func CreateWildcardNFA(alphabet []byte, statePrefix string, counter *int) *NFA {
+func CreateWildcardNFA(alphabet Alphabet, statePrefix string, counter *int) *NFA {
 	start := fmt.Sprintf("%s%d", statePrefix, *counter)
 	*counter++
 	accept := fmt.Sprintf("%s%d", statePrefix, *counter)
@@ -387,8 +382,8 @@ func CreateWildcardNFA(alphabet []byte, statePrefix string, counter *int) *NFA {
 
 	delta := make(DeltaNfa)
 
-	// Create a transition for each character in the alphabet
-	for _, symbol := range alphabet {
+	// Create a transition for each range in the alphabet
+	for _, symbol := range alphabet.Normalize() {
 		if symbol != Epsilon {
 			delta.Add(start, symbol, NewSetState(accept))
 		}
@@ -425,8 +420,8 @@ func ConcatenateNFAs(first, second *NFA) *NFA {
 		}
 	}
 
-	sigma := make([]byte, 0)
-	symbolSet := make(map[byte]bool)
+	sigma := make(Alphabet, 0)
+	symbolSet := make(map[RuneRange]bool)
 
 	for _, symbol := range first.Sigma {
 		if !symbolSet[symbol] {
@@ -497,8 +492,8 @@ func UnionNFAs(first, second *NFA, statePrefix string, counter *int) *NFA {
 		delta.Add(finalState, Epsilon, NewSetState(accept))
 	}
 
-	sigma := make([]byte, 0)
-	symbolSet := make(map[byte]bool)
+	sigma := make(Alphabet, 0)
+	symbolSet := make(map[RuneRange]bool)
 
 	for _, symbol := range first.Sigma {
 		if !symbolSet[symbol] {
@@ -563,7 +558,7 @@ func StarNFA(nfa *NFA, statePrefix string, counter *int) *NFA {
 
 	states := append([]State{start, accept}, nfa.Q...)
 
-	sigma := append([]byte{}, nfa.Sigma...)
+	sigma := append(Alphabet{}, nfa.Sigma...)
 	hasEpsilon := false
 	for _, symbol := range sigma {
 		if symbol == Epsilon {
@@ -584,22 +579,10 @@ func StarNFA(nfa *NFA, statePrefix string, counter *int) *NFA {
 	)
 }
 
-// PlusNFA creates a plus NFA (one or more repetitions)
+// PlusNFA creates a plus NFA (one or more repetitions). The mandatory first
+// copy gets fresh state names, so it shares no state with the starred copy.
 func PlusNFA(nfa *NFA, statePrefix string, counter *int) *NFA {
-	firstCopy := &NFA{
-		Q:     append([]State{}, nfa.Q...),
-		Sigma: append([]byte{}, nfa.Sigma...),
-		Delta: make(DeltaNfa),
-		Q0:    append([]State{}, nfa.Q0...),
-		F:     append([]State{}, nfa.F...),
-	}
-
-	for state, transitions := range nfa.Delta {
-		for symbol, states := range transitions {
-			firstCopy.Delta.Add(state, symbol, states)
-		}
-	}
-
+	firstCopy := renameNFA(nfa, statePrefix, counter)
 	starVersion := StarNFA(nfa, statePrefix, counter)
 
 	return ConcatenateNFAs(firstCopy, starVersion)
@@ -632,7 +615,7 @@ func QuestionNFA(nfa *NFA, statePrefix string, counter *int) *NFA {
 
 	states := append([]State{start, accept}, nfa.Q...)
 
-	sigma := append([]byte{}, nfa.Sigma...)
+	sigma := append(Alphabet{}, nfa.Sigma...)
 	hasEpsilon := false
 	for _, symbol := range sigma {
 		if symbol == Epsilon {
@@ -761,8 +744,8 @@ func RepeatNFA(nfa *NFA, n int) *NFA {
 		}
 	}
 
-	sigma := make([]byte, 0)
-	symbolSet := make(map[byte]bool)
+	sigma := make(Alphabet, 0)
+	symbolSet := make(map[RuneRange]bool)
 	for _, copy := range copies {
 		for _, symbol := range copy.Sigma {
 			if !symbolSet[symbol] {
@@ -785,10 +768,92 @@ func RepeatNFA(nfa *NFA, n int) *NFA {
 	)
 }
 
+// RepeatRangeNFA creates an NFA accepting between min and max repetitions of
+// nfa, or at least min when max is negative. It is built as min mandatory
+// copies followed by either max-min optional copies or a starred copy. Every
+// copy gets fresh state names from the shared counter, so several repetitions
+// can live in the same automaton.
+func RepeatRangeNFA(nfa *NFA, min, max int, statePrefix string, counter *int) *NFA {
+	var result *NFA
+	appendCopy := func(part *NFA) {
+		if result == nil {
+			result = part
+		} else {
+			result = ConcatenateNFAs(result, part)
+		}
+	}
+
+	for i := 0; i < min; i++ {
+		appendCopy(renameNFA(nfa, statePrefix, counter))
+	}
+
+	if max < 0 {
+		appendCopy(StarNFA(renameNFA(nfa, statePrefix, counter), statePrefix, counter))
+	} else {
+		for i := min; i < max; i++ {
+			appendCopy(QuestionNFA(renameNFA(nfa, statePrefix, counter), statePrefix, counter))
+		}
+	}
+
+	if result == nil {
+		return CreateEmptyNFA(statePrefix, counter)
+	}
+	return result
+}
+
+// renameNFA returns a deep copy of nfa whose states are freshly named from
+// the counter
+func renameNFA(nfa *NFA, statePrefix string, counter *int) *NFA {
+	names := make(map[State]State, len(nfa.Q))
+	rename := func(state State) State {
+		if name, ok := names[state]; ok {
+			return name
+		}
+		name := fmt.Sprintf("%s%d", statePrefix, *counter)
+		*counter++
+		names[state] = name
+		return name
+	}
+
+	q := make([]State, 0, len(nfa.Q))
+	for _, state := range nfa.Q {
+		q = append(q, rename(state))
+	}
+
+	q0 := make([]State, 0, len(nfa.Q0))
+	for _, state := range nfa.Q0 {
+		q0 = append(q0, rename(state))
+	}
+
+	f := make([]State, 0, len(nfa.F))
+	for _, state := range nfa.F {
+		f = append(f, rename(state))
+	}
+
+	delta := make(DeltaNfa)
+	for state, transitions := range nfa.Delta {
+		for symbol, states := range transitions {
+			targets := make(setState)
+			for target := range states {
+				targets[rename(target)] = true
+			}
+			delta.Add(rename(state), symbol, targets)
+		}
+	}
+
+	return &NFA{
+		Q:     q,
+		Sigma: append(Alphabet{}, nfa.Sigma...),
+		Delta: delta,
+		Q0:    q0,
+		F:     f,
+	}
+}
+
 // Helper function to create a deep copy of an NFA
 func deepCopyNFA(nfa *NFA) *NFA {
 	q := make([]State, len(nfa.Q))
-	sigma := make([]byte, len(nfa.Sigma))
+	sigma := make(Alphabet, len(nfa.Sigma))
 	q0 := make([]State, len(nfa.Q0))
 	f := make([]State, len(nfa.F))
 
@@ -6,3 +6,97 @@ import (
 
 func TestNFAtoDFA(t *testing.T) {
 }
+
+func TestRangeTransitionsAcceptUnicode(t *testing.T) {
+	delta := make(DeltaNfa)
+	delta.Add("q0", Range('a', 'z'), NewSetState("q1"))
+	delta.Add("q0", Sym('é'), NewSetState("q1"))
+	delta.Add("q1", Range('a', 'm'), NewSetState("q1"))
+	delta.Add("q1", Range('k', 'z'), NewSetState("q2"))
+
+	nfa := NewNFA([]State{"q0", "q1", "q2"}, Alphabet{}, delta, []State{"q0"}, []State{"q1", "q2"})
+	dfa := nfa.ToDFA()
+
+	for _, w := range []string{"é", "ab", "éz", "akz"} {
+		if !nfa.Accept(w) || !dfa.Accept(w) {
+			t.Fatal("word: ", w, " Rejected")
+		}
+	}
+
+	// 'è' is a neighbour of 'é' but is not part of any label
+	for _, w := range []string{"è", "zé", "A", ""} {
+		if nfa.Accept(w) || dfa.Accept(w) {
+			t.Fatal("word: ", w, " Accepted")
+		}
+	}
+
+	// The overlapping [a-m] and [k-z] labels must be split for the DFA
+	for _, r := range dfa.labels() {
+		if r.Lo <= 'm' && r.Hi >= 'k' && !(r.Lo >= 'k' && r.Hi <= 'm') {
+			t.Fatalf("range %v straddles a split point", r)
+		}
+	}
+}
+
+func TestToDFAKeepsAlphabet(t *testing.T) {
+	delta := make(DeltaNfa)
+	delta.Add("q0", Sym('a'), NewSetState("q0"))
+	nfa := NewNFA([]State{"q0"}, NewAlphabet('a', 'b'), delta, []State{"q0"}, []State{"q0"})
+
+	// b has no transition but still belongs to the alphabet
+	dfa := nfa.ToDFA()
+	if got := dfa.Sigma.String(); got != "[ab]" {
+		t.Errorf("expected Sigma [ab], got %s", got)
+	}
+	if dfa.Stats().Complete {
+		t.Error("the DFA has no transition on b")
+	}
+	complement := dfa.Complement(dfa.Sigma)
+	assert(t, complement.Accept("ab"), "the complement should accept ab")
+}
+
+func TestNewNFAAddsOnlyMissingRunes(t *testing.T) {
+	delta := make(DeltaNfa)
+	delta.Add("q0", Sym('a'), NewSetState("q1"))
+	delta.Add("q1", Range('b', 'e'), NewSetState("q1"))
+	nfa := NewNFA([]State{"q0", "q1"}, Alphabet{Range('a', 'c')}, delta, []State{"q0"}, []State{"q1"})
+
+	// a is already declared and only d-e is missing
+	if len(nfa.Sigma) != 2 || nfa.Sigma[1] != Range('d', 'e') {
+		t.Errorf("expected Sigma [a-c] and [de], got %v", nfa.Sigma)
+	}
+}
+
+func TestRuneRangeLabels(t *testing.T) {
+	cases := []struct {
+		got  string
+		want string
+	}{
+		{Sym('a').String(), "a"},
+		{Range('a', 'z').String(), "[a-z]"},
+		{Epsilon.String(), "ε"},
+		{Alphabet{Range('0', '9'), Sym('_')}.String(), "[0-9_]"},
+		{Alphabet{Range('a', 'c'), Range('b', 'f')}.String(), "[a-f]"},
+	}
+	for _, c := range cases {
+		if c.got != c.want {
+			t.Errorf("got label %q, want %q", c.got, c.want)
+		}
+	}
+}
+
+func TestComplementOfSubsetDFA(t *testing.T) {
+	// Sigma [a-z] is wider than the labels [a-m] and [k-z] of the DFA
+	delta := make(DeltaNfa)
+	delta.Add("q0", Range('a', 'm'), NewSetState("q1"))
+	delta.Add("q0", Range('k', 'z'), NewSetState("q2"))
+	nfa := NewNFA([]State{"q0", "q1", "q2"}, Alphabet{Range('a', 'z')}, delta, []State{"q0"}, []State{"q1"})
+
+	complement := nfa.ToDFA().Complement(nfa.Sigma)
+	for _, w := range []string{"a", "l", "m"} {
+		assert(t, !complement.Accept(w), "the complement should reject "+w)
+	}
+	for _, w := range []string{"", "n", "z", "ab"} {
+		assert(t, complement.Accept(w), "the complement should accept "+w)
+	}
+}
@@ -0,0 +1,211 @@
+package lfa
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// AcceptMode selects how a PDA accepts its input
+type AcceptMode int
+
+const (
+	// AcceptByFinalState accepts when the input is consumed in a state of F
+	AcceptByFinalState AcceptMode = iota
+	// AcceptByEmptyStack accepts when the input is consumed with an empty stack
+	AcceptByEmptyStack
+)
+
+// PDAMove is a single PDA transition: reading Input (or Epsilon) with Pop on
+// top of the stack, go to To and replace Pop by Push. The first rune of Push
+// ends up on top of the stack; an empty Push just pops.
+type PDAMove struct {
+	Input RuneRange
+	Pop   rune
+	To    State
+	Push  string
+}
+
+// Label renders the move as "a, X / YZ"
+func (m PDAMove) Label() string {
+	push := m.Push
+	if push == "" {
+		push = "ε"
+	}
+	return fmt.Sprintf("%s, %c / %s", m.Input, m.Pop, push)
+}
+
+type DeltaPDA map[State][]PDAMove
+
+func (d DeltaPDA) Add(in State, input RuneRange, pop rune, out State, push string) {
+	d[in] = append(d[in], PDAMove{Input: input, Pop: pop, To: out, Push: push})
+}
+
+// PDA is a nondeterministic pushdown automaton
+type PDA struct {
+	Q     []State
+	Sigma Alphabet
+	Gamma []rune
+	Delta DeltaPDA
+	Q0    State
+	Z0    rune
+	F     []State
+	Mode  AcceptMode
+}
+
+func NewPDA(Q []State, Sigma Alphabet, Gamma []rune, Delta DeltaPDA, q0 State, z0 rune, F []State, mode AcceptMode) *PDA {
+	return &PDA{
+		Q:     Q,
+		Sigma: Sigma,
+		Gamma: Gamma,
+		Delta: Delta,
+		Q0:    q0,
+		Z0:    z0,
+		F:     F,
+		Mode:  mode,
+	}
+}
+
+// PDAConfig is an instantaneous description (state, remaining input, stack),
+// with the top of the stack first
+type PDAConfig struct {
+	State State
+	Input string
+	Stack string
+}
+
+func (c PDAConfig) String() string {
+	input, stack := c.Input, c.Stack
+	if input == "" {
+		input = "ε"
+	}
+	if stack == "" {
+		stack = "ε"
+	}
+	return fmt.Sprintf("(%s, %s, %s)", c.State, input, stack)
+}
+
+func (p *PDA) accepting(c PDAConfig) bool {
+	if c.Input != "" {
+		return false
+	}
+	if p.Mode == AcceptByEmptyStack {
+		return c.Stack == ""
+	}
+	return contains(p.F, c.State)
+}
+
+// next lists the configurations reachable from c in one move
+func (p *PDA) next(c PDAConfig) []PDAConfig {
+	if c.Stack == "" {
+		return nil
+	}
+
+	stack := []rune(c.Stack)
+	top, rest := stack[0], string(stack[1:])
+	input := []rune(c.Input)
+
+	out := make([]PDAConfig, 0)
+	for _, m := range p.Delta[c.State] {
+		if m.Pop != top {
+			continue
+		}
+		switch {
+		case m.Input.IsEpsilon():
+			out = append(out, PDAConfig{m.To, c.Input, m.Push + rest})
+		case len(input) > 0 && m.Input.Contains(input[0]):
+			out = append(out, PDAConfig{m.To, string(input[1:]), m.Push + rest})
+		}
+	}
+	return out
+}
+
+// Trace simulates the PDA on s with a breadth-first search over
+// configurations and returns the sequence of configurations of the shortest
+// accepting computation, or nil if s is rejected. Since epsilon moves can
+// grow the stack forever, the search explores at most maxSteps
+// configurations; running out of steps is reported as an error, as the
+// answer is then unknown.
+func (p *PDA) Trace(s string, maxSteps int) ([]PDAConfig, error) {
+	start := PDAConfig{p.Q0, s, string(p.Z0)}
+
+	parent := map[PDAConfig]*PDAConfig{start: nil}
+	queue := []PDAConfig{start}
+
+	for steps := 0; len(queue) > 0; steps++ {
+		if steps >= maxSteps {
+			return nil, fmt.Errorf("step bound of %d configurations exceeded", maxSteps)
+		}
+
+		c := queue[0]
+		queue = queue[1:]
+
+		if p.accepting(c) {
+			path := make([]PDAConfig, 0)
+			for at := &c; at != nil; at = parent[*at] {
+				path = append(path, *at)
+			}
+			for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+				path[i], path[j] = path[j], path[i]
+			}
+			return path, nil
+		}
+
+		for _, n := range p.next(c) {
+			if _, seen := parent[n]; !seen {
+				from := c
+				parent[n] = &from
+				queue = append(queue, n)
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// Accept checks whether the PDA accepts s, exploring at most maxSteps
+// configurations
+func (p *PDA) Accept(s string, maxSteps int) (bool, error) {
+	path, err := p.Trace(s, maxSteps)
+	if err != nil {
+		return false, err
+	}
+	return path != nil, nil
+}
+
+func (p *PDA) ToDOT(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	fmt.Fprintln(file, "digraph PDA {")
+	fmt.Fprintln(file, "  rankdir=LR;")
+	fmt.Fprintln(file, "  node [shape=circle];")
+
+	// Mark final states
+	for _, f := range p.F {
+		fmt.Fprintf(file, "  \"%s\" [shape=doublecircle];\n", f)
+	}
+
+	// Mark initial state
+	fmt.Fprintf(file, "  \"\" [shape=none];\n")
+	fmt.Fprintf(file, "  \"\" -> \"%s\";\n", p.Q0)
+
+	// Add transitions, stacking the labels of parallel moves
+	for state, moves := range p.Delta {
+		labels := make(map[State][]string)
+		for _, m := range moves {
+			labels[m.To] = append(labels[m.To], dotEscape(m.Label()))
+		}
+		for nextState, l := range labels {
+			sort.Strings(l)
+			fmt.Fprintf(file, "  \"%s\" -> \"%s\" [label=\"%s\"];\n", state, nextState, strings.Join(l, "\\n"))
+		}
+	}
+
+	fmt.Fprintln(file, "}")
+	return nil
+}
@@ -0,0 +1,92 @@
+package lfa
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// anbn accepts a^n b^n (n ≥ 0)
+func anbn(mode AcceptMode) *PDA {
+	delta := make(DeltaPDA)
+	delta.Add("q0", Sym('a'), 'Z', "q0", "AZ")
+	delta.Add("q0", Sym('a'), 'A', "q0", "AA")
+	delta.Add("q0", Sym('b'), 'A', "q1", "")
+	delta.Add("q1", Sym('b'), 'A', "q1", "")
+	delta.Add("q0", Epsilon, 'Z', "q2", "Z")
+	delta.Add("q1", Epsilon, 'Z', "q2", "Z")
+	delta.Add("q2", Epsilon, 'Z', "q2", "")
+
+	return NewPDA([]State{"q0", "q1", "q2"}, NewAlphabet('a', 'b'), []rune{'Z', 'A'},
+		delta, "q0", 'Z', []State{"q2"}, mode)
+}
+
+func TestPDAAcceptModes(t *testing.T) {
+	for _, mode := range []AcceptMode{AcceptByFinalState, AcceptByEmptyStack} {
+		p := anbn(mode)
+		for _, w := range []string{"", "ab", "aabb", "aaabbb"} {
+			if ok, err := p.Accept(w, 1000); err != nil || !ok {
+				t.Fatal("word: ", w, " Rejected ", err)
+			}
+		}
+		for _, w := range []string{"a", "b", "ba", "aab", "abab"} {
+			if ok, err := p.Accept(w, 1000); err != nil || ok {
+				t.Fatal("word: ", w, " Accepted ", err)
+			}
+		}
+	}
+}
+
+func TestPDANondeterministicPalindromes(t *testing.T) {
+	// Even-length palindromes: guess the middle with an epsilon move
+	delta := make(DeltaPDA)
+	for _, c := range []rune{'a', 'b'} {
+		for _, top := range []rune{'Z', 'a', 'b'} {
+			delta.Add("push", Sym(c), top, "push", string(c)+string(top))
+		}
+		delta.Add("pop", Sym(c), c, "pop", "")
+	}
+	for _, top := range []rune{'Z', 'a', 'b'} {
+		delta.Add("push", Epsilon, top, "pop", string(top))
+	}
+	delta.Add("pop", Epsilon, 'Z', "done", "Z")
+
+	p := NewPDA([]State{"push", "pop", "done"}, NewAlphabet('a', 'b'), []rune{'Z', 'a', 'b'},
+		delta, "push", 'Z', []State{"done"}, AcceptByFinalState)
+
+	trace, err := p.Trace("abba", 1000)
+	if err != nil || trace == nil {
+		t.Fatal("word: abba Rejected ", err)
+	}
+	t.Log("trace: ", trace)
+	if last := trace[len(trace)-1]; last.State != "done" || last.Input != "" {
+		t.Fatalf("trace should end in an accepting configuration, got %v", last)
+	}
+
+	if ok, _ := p.Accept("abab", 1000); ok {
+		t.Fatal("word: abab Accepted")
+	}
+}
+
+func TestPDAStepBoundAndDOT(t *testing.T) {
+	// An epsilon loop that keeps pushing never decides a rejected word
+	delta := make(DeltaPDA)
+	delta.Add("q0", Epsilon, 'Z', "q0", "ZZ")
+	p := NewPDA([]State{"q0"}, NewAlphabet('a'), []rune{'Z'}, delta, "q0", 'Z', nil, AcceptByFinalState)
+
+	if _, err := p.Accept("a", 50); err == nil {
+		t.Fatal("expected the step bound to be reported")
+	}
+
+	file := filepath.Join(t.TempDir(), "pda.dot")
+	if err := anbn(AcceptByFinalState).ToDOT(file); err != nil {
+		t.Fatal(err)
+	}
+	dot, _ := os.ReadFile(file)
+	for _, label := range []string{"a, Z / AZ", "b, A / ε", "ε, Z / ε"} {
+		if !strings.Contains(string(dot), label) {
+			t.Errorf("DOT output is missing edge label %q", label)
+		}
+	}
+}
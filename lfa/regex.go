@@ -3,6 +3,9 @@ package lfa
 import (
 	"fmt"
 	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 // Regex represents a regular expression parser
@@ -11,18 +14,20 @@ type Regex struct {
 	position     int
 	stateCounter int
 	statePrefix  string
-	alphabet     []byte // Alphabet for wildcard character
+	alphabet     Alphabet // Alphabet for wildcard character
+	groups       int      // Number of capturing groups parsed so far
+	steps        []ThompsonStep
+	foldCase     bool     // Set by the (?i) flag
+	declared     Alphabet // Alphabet declared in the pattern, nil if none
 }
 
 // NewRegex creates a new regex parser
 func NewRegex(expression string) *Regex {
 	// Default alphabet includes lowercase letters, uppercase letters, and digits
-	defaultAlphabet := []byte{
-		'a', 'b', 'c', 'd', 'e', 'f', 'g', 'h', 'i', 'j', 'k', 'l', 'm',
-		'n', 'o', 'p', 'q', 'r', 's', 't', 'u', 'v', 'w', 'x', 'y', 'z',
-		'A', 'B', 'C', 'D', 'E', 'F', 'G', 'H', 'I', 'J', 'K', 'L', 'M',
-		'N', 'O', 'P', 'Q', 'R', 'S', 'T', 'U', 'V', 'W', 'X', 'Y', 'Z',
-		'0', '1', '2', '3', '4', '5', '6', '7', '8', '9',
+	defaultAlphabet := Alphabet{
+		Range('a', 'z'),
+		Range('A', 'Z'),
+		Range('0', '9'),
 	}
 
 	return &Regex{
@@ -35,105 +40,252 @@ func NewRegex(expression string) *Regex {
 }
 
 // SetAlphabet allows setting the alphabet used for wildcard characters
-func (r *Regex) SetAlphabet(alphabet []byte) {
+func (r *Regex) SetAlphabet(alphabet Alphabet) {
 	r.alphabet = alphabet
 }
 
 // Parse converts a regular expression to an NFA
 func (r *Regex) Parse() (*NFA, error) {
-	return r.parseExpression()
+	ast, err := r.ParseAST()
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckAnchors(ast); err != nil {
+		return nil, err
+	}
+	nfa := r.Compile(ast)
+	if r.declared != nil {
+		nfa = NewNFA(nfa.Q, r.withDeclaredAlphabet(nil), nfa.Delta, nfa.Q0, nfa.F)
+	}
+	return nfa, nil
 }
 
-// parseExpression parses a full expression (possibly with alternation)
-func (r *Regex) parseExpression() (*NFA, error) {
-	// Parse the first term
-	nfa, err := r.parseTerm()
+// ParseAST parses the regular expression into its abstract syntax tree.
+// Syntax errors are reported as *RegexError.
+func (r *Regex) ParseAST() (RegexNode, error) {
+	r.position = 0
+	r.groups = 0
+	r.steps = nil
+	r.foldCase = false
+	r.declared = nil
+	node, err := r.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	// parseExpression only stops early at an unmatched ')'
+	if r.position < len(r.expression) {
+		return nil, r.errorAt(r.position, "end of pattern")
+	}
+	return node, nil
+}
+
+// parseExpression parses a full expression (possibly with alternation).
+//
+// Operators bind, from loosest to tightest: alternation '|', intersection
+// '&', concatenation, complement '~' and the postfix quantifiers. So
+// "a|b&c" is "a|(b&c)", "ab&cd" is "(ab)&(cd)" and "~a*b" is "(~(a*))b".
+func (r *Regex) parseExpression() (RegexNode, error) {
+	// Parse the first intersection
+	node, err := r.parseIntersection()
 	if err != nil {
 		return nil, err
 	}
 
 	// Check for alternation (|)
+	options := []RegexNode{node}
 	for r.position < len(r.expression) && r.expression[r.position] == '|' {
 		r.position++ // Skip the '|'
 
-		// Parse the term after '|'
-		right, err := r.parseTerm()
+		// Parse the intersection after '|'
+		right, err := r.parseIntersection()
 		if err != nil {
 			return nil, err
 		}
 
-		// Union the current NFA with the new term
-		nfa = UnionNFAs(nfa, right, r.statePrefix, &r.stateCounter)
+		options = append(options, right)
 	}
 
-	return nfa, nil
-}
-
-// parseTerm parses a sequence of factors
-func (r *Regex) parseTerm() (*NFA, error) {
-	// Handle empty term
-	if r.position >= len(r.expression) ||
-		r.expression[r.position] == ')' ||
-		r.expression[r.position] == '|' {
-		return CreateEmptyNFA(r.statePrefix, &r.stateCounter), nil
+	if len(options) == 1 {
+		return node, nil
 	}
+	return AltNode{Options: options}, nil
+}
 
-	// Parse the first factor
-	nfa, err := r.parseFactor()
+// parseIntersection parses terms joined by '&'
+func (r *Regex) parseIntersection() (RegexNode, error) {
+	node, err := r.parseTerm()
 	if err != nil {
 		return nil, err
 	}
 
-	// Continue parsing factors and concatenate them
+	options := []RegexNode{node}
+	for r.position < len(r.expression) && r.expression[r.position] == '&' {
+		r.position++ // Skip the '&'
+
+		right, err := r.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+
+		options = append(options, right)
+	}
+
+	if len(options) == 1 {
+		return node, nil
+	}
+	return AndNode{Options: options}, nil
+}
+
+// parseTerm parses a sequence of factors
+func (r *Regex) parseTerm() (RegexNode, error) {
+	// Parse factors and concatenate them. Groups that only set flags
+	// produce no factor.
+	parts := []RegexNode{}
 	for r.position < len(r.expression) &&
 		r.expression[r.position] != ')' &&
-		r.expression[r.position] != '|' {
+		r.expression[r.position] != '|' &&
+		r.expression[r.position] != '&' {
 
-		// Parse the next factor
 		next, err := r.parseFactor()
 		if err != nil {
 			return nil, err
 		}
-
-		// Concatenate the current NFA with the new factor
-		nfa = ConcatenateNFAs(nfa, next)
+		if next != nil {
+			parts = append(parts, next)
+		}
 	}
 
-	return nfa, nil
+	switch len(parts) {
+	case 0:
+		return EpsilonNode{}, nil
+	case 1:
+		return parts[0], nil
+	}
+	return ConcatNode{Parts: parts}, nil
 }
 
-// parseFactor parses a basic unit with potential repetition (*,+,?,{n},^)
-func (r *Regex) parseFactor() (*NFA, error) {
-	var nfa *NFA
+// parseFactor parses a basic unit with potential repetition (*,+,?,{n},{m,n},ⁿ)
+func (r *Regex) parseFactor() (RegexNode, error) {
+	var node RegexNode
 	var err error
 
 	// Parse the basic unit
 	if r.position >= len(r.expression) {
-		return nil, fmt.Errorf("unexpected end of expression")
+		return nil, r.errorAt(r.position, "an expression")
+	}
+	if r.atQuantifier() {
+		return nil, r.errorAt(r.position, "an expression before the quantifier")
 	}
 
+	// Groups return nodes already adapted to the flags active inside them
+	start, group := r.position, false
 	switch r.expression[r.position] {
+	case '~': // Complement of the following factor, relative to the alphabet
+		r.position++
+		subStart := r.position
+		if subStart >= len(r.expression) || strings.IndexByte(")|&", r.expression[subStart]) >= 0 {
+			return nil, r.errorAt(subStart, "an expression after '~'")
+		}
+		sub, err := r.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		if sub == nil {
+			return nil, r.errorAt(subStart, "an expression after '~'")
+		}
+		return NotNode{Sub: sub}, nil
+
 	case '(':
 		r.position++ // Skip '('
-		nfa, err = r.parseExpression()
+		group = true
+
+		// "(?:" opens a non-capturing group, "(?" followed by anything else
+		// sets flags, and any other group captures
+		rest := r.expression[r.position:]
+		if strings.HasPrefix(rest, "?") && !strings.HasPrefix(rest, "?:") {
+			r.position++ // Skip '?'
+			node, err = r.parseFlagGroup(start)
+			if err != nil || node == nil {
+				return nil, err
+			}
+			break
+		}
+
+		capturing := !strings.HasPrefix(rest, "?:")
+		index := 0
+		if capturing {
+			r.groups++
+			index = r.groups
+		} else {
+			r.position += 2 // Skip '?:'
+		}
+
+		// Flags set inside the group end with it
+		fold := r.foldCase
+		contentStart := r.position
+		node, err = r.parseExpression()
 		if err != nil {
 			return nil, err
 		}
 
 		if r.position >= len(r.expression) || r.expression[r.position] != ')' {
-			return nil, fmt.Errorf("missing closing parenthesis")
+			return nil, r.errorAt(r.position, "')'")
 		}
+		empty := r.position == contentStart
 		r.position++ // Skip ')'
+		r.foldCase = fold
+
+		// An empty group matches the empty string and cannot be repeated
+		if empty && r.atQuantifier() {
+			return nil, r.errorAt(r.position, "a non-empty group before the quantifier")
+		}
 
-	case '.': // Dot matches any character
+		if capturing {
+			node = GroupNode{Sub: node, Index: index}
+		}
+
+	case '^': // Start of input
 		r.position++
-		nfa = CreateWildcardNFA(r.alphabet, r.statePrefix, &r.stateCounter)
+		node = AnchorNode{}
 
-	default:
-		// Standard character
-		char := r.expression[r.position]
+	case '$': // End of input
+		r.position++
+		node = AnchorNode{End: true}
+
+	case '.': // Dot matches any character of the alphabet
 		r.position++
-		nfa = CreateBasicNFA(char, r.statePrefix, &r.stateCounter)
+		node = ClassNode{Negated: true}
+
+	case '[': // Bracket expression
+		node, err = r.parseClass()
+		if err != nil {
+			return nil, err
+		}
+
+	case '\\': // Escaped metacharacter or shorthand class
+		char, class, err := r.parseEscape()
+		if err != nil {
+			return nil, err
+		}
+		if class != nil {
+			node = *class
+		} else {
+			node = LiteralNode{Char: char}
+		}
+
+	default:
+		// Standard character, decoded as a full UTF-8 rune
+		char, size := utf8.DecodeRuneInString(r.expression[r.position:])
+		r.position += size
+		node = LiteralNode{Char: char}
+	}
+
+	if !group {
+		node, err = r.applyFlags(node, start)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Check for repetition operators
@@ -141,47 +293,240 @@ func (r *Regex) parseFactor() (*NFA, error) {
 		switch r.expression[r.position] {
 		case '*':
 			r.position++
-			nfa = StarNFA(nfa, r.statePrefix, &r.stateCounter)
+			node = StarNode{Sub: node}
 
 		case '+':
 			r.position++
-			nfa = PlusNFA(nfa, r.statePrefix, &r.stateCounter)
+			node = PlusNode{Sub: node}
 
 		case '?':
 			r.position++
-			nfa = QuestionNFA(nfa, r.statePrefix, &r.stateCounter)
+			node = OptionalNode{Sub: node}
 
-		case '^': // Power operator for repetition (similar to {n})
+		case '{':
 			r.position++
-			if r.position >= len(r.expression) {
-				return nil, fmt.Errorf("expected number after ^ operator")
-			}
-
-			// Expect a number after ^ operator
-			count, err := r.parseNumber()
+			min, max, err := r.parseBounds()
 			if err != nil {
 				return nil, err
 			}
 
-			nfa = RepeatNFA(nfa, count)
+			node = RepeatNode{Sub: node, Min: min, Max: max}
 
-		case '{':
+		default:
+			// Power operator written with superscript digits, a³ = a{3}
+			count, ok := r.parsePower()
+			if !ok {
+				return node, nil
+			}
+			node = RepeatNode{Sub: node, Min: count, Max: count}
+		}
+
+		// Lazy quantifiers (*?, +?, ??, {m,n}?) are accepted for compatibility.
+		// Leftmost-longest matching does not depend on laziness, so they
+		// compile to the same automaton.
+		if r.position < len(r.expression) && r.expression[r.position] == '?' {
 			r.position++
-			count, err := r.parseNumber()
+		}
+
+		// Quantifiers cannot be stacked, e.g. a** or a+*; use a group
+		if r.atQuantifier() {
+			return nil, r.errorAt(r.position, "an expression before the quantifier")
+		}
+	}
+
+	return node, nil
+}
+
+// atQuantifier reports whether a quantifier starts at the current position
+func (r *Regex) atQuantifier() bool {
+	if r.position >= len(r.expression) {
+		return false
+	}
+	char, _ := utf8.DecodeRuneInString(r.expression[r.position:])
+	return strings.ContainsRune("*+?{"+superscripts, char)
+}
+
+// Shorthand classes available as \d, \w and \s; their upper-case forms are
+// complemented relative to the regex alphabet
+var (
+	digitClass = Alphabet{Range('0', '9')}
+	wordClass  = Alphabet{Range('a', 'z'), Range('A', 'Z'), Range('0', '9'), Sym('_')}
+	spaceClass = Alphabet{Sym(' '), Range('\t', '\r')}
+)
+
+// parseEscape parses a backslash escape. It returns either a literal rune or,
+// for shorthand classes, the class the escape matches.
+func (r *Regex) parseEscape() (rune, *ClassNode, error) {
+	r.position++ // Skip '\'
+	if r.position >= len(r.expression) {
+		return 0, nil, r.errorAt(r.position, "an escaped character after '\\'")
+	}
+
+	char, size := utf8.DecodeRuneInString(r.expression[r.position:])
+	r.position += size
+
+	switch char {
+	case 'd':
+		return 0, &ClassNode{Set: digitClass}, nil
+	case 'D':
+		return 0, &ClassNode{Set: digitClass, Negated: true}, nil
+	case 'w':
+		return 0, &ClassNode{Set: wordClass}, nil
+	case 'W':
+		return 0, &ClassNode{Set: wordClass, Negated: true}, nil
+	case 's':
+		return 0, &ClassNode{Set: spaceClass}, nil
+	case 'S':
+		return 0, &ClassNode{Set: spaceClass, Negated: true}, nil
+	case 'n':
+		return '\n', nil, nil
+	case 't':
+		return '\t', nil, nil
+	case 'r':
+		return '\r', nil, nil
+	case 'f':
+		return '\f', nil, nil
+	case 'v':
+		return '\v', nil, nil
+	}
+
+	// Metacharacters and any other punctuation stand for themselves, while
+	// letters and digits are reserved for shorthands
+	if unicode.IsLetter(char) || unicode.IsDigit(char) {
+		return 0, nil, r.errorFound(r.position-size, "a known escape sequence", fmt.Sprintf("\\%c", char))
+	}
+	return char, nil, nil
+}
+
+// parseClassAtom parses a single member of a bracket expression: a literal
+// rune, an escaped rune or a shorthand class resolved against the alphabet
+func (r *Regex) parseClassAtom() (rune, Alphabet, error) {
+	if r.expression[r.position] != '\\' {
+		char, size := utf8.DecodeRuneInString(r.expression[r.position:])
+		r.position += size
+		return char, nil, nil
+	}
+
+	char, class, err := r.parseEscape()
+	if err != nil || class == nil {
+		return char, nil, err
+	}
+	if class.Negated {
+		return 0, class.Set.Complement(r.alphabet), nil
+	}
+	return 0, class.Set, nil
+}
+
+// parseClass parses a bracket expression such as [a-z0-9_] or [^ab].
+// Negation is relative to the regex alphabet, "[]" is the empty set and a
+// '-' at either end is a literal.
+func (r *Regex) parseClass() (ClassNode, error) {
+	r.position++ // Skip '['
+
+	negate := false
+	if r.position < len(r.expression) && r.expression[r.position] == '^' {
+		negate = true
+		r.position++
+	}
+
+	class := Alphabet{}
+	for {
+		if r.position >= len(r.expression) {
+			return ClassNode{}, r.errorAt(r.position, "']'")
+		}
+		if r.expression[r.position] == ']' {
+			r.position++ // Skip ']'
+			break
+		}
+
+		loStart := r.position
+		lo, set, err := r.parseClassAtom()
+		if err != nil {
+			return ClassNode{}, err
+		}
+		if set != nil {
+			class = append(class, set...)
+			continue
+		}
+
+		// A '-' between two atoms makes a range, unless it closes the class
+		if r.position+1 < len(r.expression) &&
+			r.expression[r.position] == '-' &&
+			r.expression[r.position+1] != ']' {
+			r.position++ // Skip '-'
+
+			hiStart := r.position
+			hi, set, err := r.parseClassAtom()
 			if err != nil {
-				return nil, err
+				return ClassNode{}, err
 			}
-
-			if r.position >= len(r.expression) || r.expression[r.position] != '}' {
-				return nil, fmt.Errorf("missing closing brace")
+			if set != nil {
+				return ClassNode{}, r.errorFound(hiStart, "a character to end the range", "a shorthand class")
 			}
-			r.position++ // Skip '}'
+			if hi < lo {
+				return ClassNode{}, r.errorFound(loStart, "a range whose end is not below its start",
+					fmt.Sprintf("%c-%c", lo, hi))
+			}
+			class = append(class, Range(lo, hi))
+			continue
+		}
+
+		class = append(class, Sym(lo))
+	}
+
+	return ClassNode{Set: class.Normalize(), Negated: negate}, nil
+}
+
+// parseBounds parses the inside of a {n}, {m,n}, {m,} or {,n} quantifier
+// after the opening brace. An unbounded maximum is returned as -1.
+func (r *Regex) parseBounds() (int, int, error) {
+	brace := r.position - 1 // the '{'
+	isDigit := func() bool {
+		return r.position < len(r.expression) &&
+			r.expression[r.position] >= '0' && r.expression[r.position] <= '9'
+	}
+
+	min, max := 0, -1
+	hasMin, hasMax := isDigit(), false
 
-			nfa = RepeatNFA(nfa, count)
+	if hasMin {
+		count, err := r.parseNumber()
+		if err != nil {
+			return 0, 0, err
 		}
+		min = count
 	}
 
-	return nfa, nil
+	if r.position < len(r.expression) && r.expression[r.position] == ',' {
+		r.position++ // Skip ','
+		if isDigit() {
+			count, err := r.parseNumber()
+			if err != nil {
+				return 0, 0, err
+			}
+			max, hasMax = count, true
+		}
+		if !hasMin && !hasMax {
+			return 0, 0, r.errorAt(r.position, "at least one bound in the quantifier")
+		}
+	} else {
+		if !hasMin {
+			return 0, 0, r.errorAt(r.position, "a number in the quantifier")
+		}
+		max = min
+	}
+
+	if r.position >= len(r.expression) || r.expression[r.position] != '}' {
+		return 0, 0, r.errorAt(r.position, "'}'")
+	}
+	r.position++ // Skip '}'
+
+	if max >= 0 && min > max {
+		return 0, 0, r.errorFound(brace, "a minimum not greater than the maximum",
+			fmt.Sprintf("{%d,%d}", min, max))
+	}
+
+	return min, max, nil
 }
 
 // parseNumber parses a number in curly braces {n}
@@ -196,18 +541,39 @@ func (r *Regex) parseNumber() (int, error) {
 	}
 
 	if start == r.position {
-		return 0, fmt.Errorf("expected number in quantifier")
+		return 0, r.errorAt(r.position, "a number in the quantifier")
 	}
 
 	// Parse the number
 	number, err := strconv.Atoi(r.expression[start:r.position])
 	if err != nil {
-		return 0, fmt.Errorf("invalid number in quantifier: %v", err)
+		return 0, r.errorFound(start, "a number in the quantifier", fmt.Sprintf("%s (%v)", r.expression[start:r.position], err))
 	}
 
 	return number, nil
 }
 
+// superscripts lists the superscript digits ⁰ to ⁹ used by the power operator
+const superscripts = "⁰¹²³⁴⁵⁶⁷⁸⁹"
+
+// parsePower parses a power written with superscript digits, such as the ¹²
+// of a¹², and reports whether there was one
+func (r *Regex) parsePower() (int, bool) {
+	count, found := 0, false
+	for r.position < len(r.expression) {
+		char, size := utf8.DecodeRuneInString(r.expression[r.position:])
+		digit := strings.IndexRune(superscripts, char)
+		if digit < 0 {
+			break
+		}
+		// Superscripts are not contiguous in Unicode, so count runes
+		count = count*10 + utf8.RuneCountInString(superscripts[:digit])
+		r.position += size
+		found = true
+	}
+	return count, found
+}
+
 // CreateNFAFromRegex creates an NFA that accepts the language described by the regular expression
 func CreateNFAFromRegex(pattern string) (*NFA, error) {
 	regex := NewRegex(pattern)
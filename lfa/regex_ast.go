@@ -0,0 +1,543 @@
+package lfa
+
+import (
+	"fmt"
+	"strings"
+)
+
+type RegexKind int
+
+const (
+	RegexEmpty RegexKind = iota
+	RegexEpsilon
+	RegexLiteral
+	RegexClass
+	RegexConcat
+	RegexAlt
+	RegexStar
+	RegexPlus
+	RegexOptional
+	RegexRepeat
+	RegexAnd
+	RegexNot
+	RegexGroup
+	RegexAnchor
+)
+
+// RegexNode is a node of the abstract syntax tree produced by the regex
+// parser. String renders the node back in the syntax accepted by the parser.
+type RegexNode interface {
+	Kind() RegexKind
+	String() string
+}
+
+// EmptyNode matches nothing (the empty language ∅)
+type EmptyNode struct{}
+
+func (EmptyNode) Kind() RegexKind { return RegexEmpty }
+func (EmptyNode) String() string  { return "[]" }
+
+// EpsilonNode matches only the empty string. It prints as an empty
+// non-capturing group, since "()" is an empty capturing group.
+type EpsilonNode struct{}
+
+func (EpsilonNode) Kind() RegexKind { return RegexEpsilon }
+func (EpsilonNode) String() string  { return "(?:)" }
+
+// LiteralNode matches a single rune
+type LiteralNode struct {
+	Char rune
+}
+
+func (LiteralNode) Kind() RegexKind  { return RegexLiteral }
+func (n LiteralNode) String() string { return escapeRegexRune(n.Char, false) }
+
+// ClassNode matches one rune of Set, or when Negated one rune of the regex
+// alphabet outside Set. The wildcard '.' is the negation of the empty set.
+type ClassNode struct {
+	Set     Alphabet
+	Negated bool
+}
+
+func (ClassNode) Kind() RegexKind { return RegexClass }
+func (n ClassNode) String() string {
+	set := n.Set.Normalize()
+	if n.Negated && len(set) == 0 {
+		return "."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("[")
+	if n.Negated {
+		sb.WriteString("^")
+	}
+	for _, r := range set {
+		sb.WriteString(escapeRegexRune(r.Lo, true))
+		if r.Hi > r.Lo {
+			if r.Hi > r.Lo+1 {
+				sb.WriteString("-")
+			}
+			sb.WriteString(escapeRegexRune(r.Hi, true))
+		}
+	}
+	sb.WriteString("]")
+	return sb.String()
+}
+
+// ConcatNode matches its parts one after another
+type ConcatNode struct {
+	Parts []RegexNode
+}
+
+func (ConcatNode) Kind() RegexKind { return RegexConcat }
+func (n ConcatNode) String() string {
+	var sb strings.Builder
+	for _, part := range n.Parts {
+		if part.Kind() == RegexAlt || part.Kind() == RegexAnd {
+			sb.WriteString("(?:" + part.String() + ")")
+		} else {
+			sb.WriteString(part.String())
+		}
+	}
+	return sb.String()
+}
+
+// AltNode matches any of its options
+type AltNode struct {
+	Options []RegexNode
+}
+
+func (AltNode) Kind() RegexKind { return RegexAlt }
+func (n AltNode) String() string {
+	options := make([]string, 0, len(n.Options))
+	for _, option := range n.Options {
+		options = append(options, option.String())
+	}
+	return strings.Join(options, "|")
+}
+
+// StarNode matches zero or more repetitions of Sub
+type StarNode struct {
+	Sub RegexNode
+}
+
+func (StarNode) Kind() RegexKind  { return RegexStar }
+func (n StarNode) String() string { return postfixOperand(n.Sub) + "*" }
+
+// PlusNode matches one or more repetitions of Sub
+type PlusNode struct {
+	Sub RegexNode
+}
+
+func (PlusNode) Kind() RegexKind  { return RegexPlus }
+func (n PlusNode) String() string { return postfixOperand(n.Sub) + "+" }
+
+// OptionalNode matches zero or one occurrence of Sub
+type OptionalNode struct {
+	Sub RegexNode
+}
+
+func (OptionalNode) Kind() RegexKind  { return RegexOptional }
+func (n OptionalNode) String() string { return postfixOperand(n.Sub) + "?" }
+
+// RepeatNode matches between Min and Max repetitions of Sub, or at least Min
+// when Max is negative
+type RepeatNode struct {
+	Sub      RegexNode
+	Min, Max int
+}
+
+func (RepeatNode) Kind() RegexKind { return RegexRepeat }
+func (n RepeatNode) String() string {
+	switch {
+	case n.Max < 0:
+		return fmt.Sprintf("%s{%d,}", postfixOperand(n.Sub), n.Min)
+	case n.Min == n.Max:
+		return fmt.Sprintf("%s{%d}", postfixOperand(n.Sub), n.Min)
+	}
+	return fmt.Sprintf("%s{%d,%d}", postfixOperand(n.Sub), n.Min, n.Max)
+}
+
+// AndNode matches the words matched by all of its options
+type AndNode struct {
+	Options []RegexNode
+}
+
+func (AndNode) Kind() RegexKind { return RegexAnd }
+func (n AndNode) String() string {
+	options := make([]string, 0, len(n.Options))
+	for _, option := range n.Options {
+		if option.Kind() == RegexAlt {
+			options = append(options, "(?:"+option.String()+")")
+		} else {
+			options = append(options, option.String())
+		}
+	}
+	return strings.Join(options, "&")
+}
+
+// NotNode matches the words over the regex alphabet that Sub does not match
+type NotNode struct {
+	Sub RegexNode
+}
+
+func (NotNode) Kind() RegexKind { return RegexNot }
+func (n NotNode) String() string {
+	switch n.Sub.Kind() {
+	case RegexConcat, RegexAlt, RegexAnd:
+		return "~(?:" + n.Sub.String() + ")"
+	}
+	return "~" + n.Sub.String()
+}
+
+// GroupNode is a capturing group; Index counts opening parentheses from 1
+type GroupNode struct {
+	Sub   RegexNode
+	Index int
+}
+
+func (GroupNode) Kind() RegexKind { return RegexGroup }
+func (n GroupNode) String() string {
+	if n.Sub.Kind() == RegexEpsilon {
+		return "()"
+	}
+	return "(" + n.Sub.String() + ")"
+}
+
+// AnchorNode matches the empty string at the start of the input, or at its
+// end when End is set. Whole-input matching already anchors both ends, so
+// outside of searches an anchor behaves like the empty string.
+type AnchorNode struct {
+	End bool
+}
+
+func (AnchorNode) Kind() RegexKind { return RegexAnchor }
+func (n AnchorNode) String() string {
+	if n.End {
+		return "$"
+	}
+	return "^"
+}
+
+// CheckAnchors reports an anchor that whole-input matching cannot honour.
+// Whole-input matching already anchors both ends, so a ^ leading the pattern
+// or a $ ending it behaves like the empty string, but an anchor anywhere else
+// constrains the position of the match, as in a^b or (a$)*b. Only the search
+// API of TaggedNFA supports such anchors; the whole-input compilers reject
+// them with this check.
+func CheckAnchors(node RegexNode) error {
+	var walk func(n RegexNode, leading, trailing bool) error
+	walk = func(n RegexNode, leading, trailing bool) error {
+		switch n := n.(type) {
+		case AnchorNode:
+			if (!n.End && !leading) || (n.End && !trailing) {
+				return fmt.Errorf("whole-input matching does not support %s inside the pattern", n)
+			}
+		case ConcatNode:
+			// Anchors consume nothing, so one may follow a leading anchor
+			// or precede a trailing one
+			for i, part := range n.Parts {
+				before, after := leading, trailing
+				for _, p := range n.Parts[:i] {
+					before = before && p.Kind() == RegexAnchor
+				}
+				for _, p := range n.Parts[i+1:] {
+					after = after && p.Kind() == RegexAnchor
+				}
+				if err := walk(part, before, after); err != nil {
+					return err
+				}
+			}
+		case AltNode:
+			for _, option := range n.Options {
+				if err := walk(option, leading, trailing); err != nil {
+					return err
+				}
+			}
+		case GroupNode:
+			return walk(n.Sub, leading, trailing)
+		case AndNode:
+			for _, option := range n.Options {
+				if err := walk(option, false, false); err != nil {
+					return err
+				}
+			}
+		case NotNode:
+			return walk(n.Sub, false, false)
+		case StarNode:
+			return walk(n.Sub, false, false)
+		case PlusNode:
+			return walk(n.Sub, false, false)
+		case OptionalNode:
+			return walk(n.Sub, false, false)
+		case RepeatNode:
+			return walk(n.Sub, false, false)
+		}
+		return nil
+	}
+	return walk(node, true, true)
+}
+
+// postfixOperand renders the operand of a quantifier, grouping it unless it
+// is a single atom
+func postfixOperand(n RegexNode) string {
+	switch n.Kind() {
+	case RegexLiteral, RegexClass, RegexEmpty, RegexEpsilon, RegexGroup, RegexAnchor:
+		return n.String()
+	}
+	return "(?:" + n.String() + ")"
+}
+
+// escapeRegexRune renders a rune so that the parser reads it back as the
+// same literal, inside or outside a bracket expression
+func escapeRegexRune(c rune, inClass bool) string {
+	switch c {
+	case '\n':
+		return `\n`
+	case '\t':
+		return `\t`
+	case '\r':
+		return `\r`
+	case '\f':
+		return `\f`
+	case '\v':
+		return `\v`
+	}
+
+	special := `()[]{}|&~*+?.^$\⁰¹²³⁴⁵⁶⁷⁸⁹`
+	if inClass {
+		special = `[]^-\`
+	}
+	if strings.ContainsRune(special, c) {
+		return `\` + string(c)
+	}
+	return string(c)
+}
+
+// Nullable reports whether the language of n contains the empty string
+func Nullable(n RegexNode) bool {
+	switch n := n.(type) {
+	case EpsilonNode, StarNode, OptionalNode, AnchorNode:
+		return true
+	case ConcatNode:
+		for _, part := range n.Parts {
+			if !Nullable(part) {
+				return false
+			}
+		}
+		return true
+	case AltNode:
+		for _, option := range n.Options {
+			if Nullable(option) {
+				return true
+			}
+		}
+		return false
+	case AndNode:
+		for _, option := range n.Options {
+			if !Nullable(option) {
+				return false
+			}
+		}
+		return true
+	case NotNode:
+		return !Nullable(n.Sub)
+	case GroupNode:
+		return Nullable(n.Sub)
+	case PlusNode:
+		return Nullable(n.Sub)
+	case RepeatNode:
+		return n.Min == 0 || Nullable(n.Sub)
+	}
+	return false
+}
+
+// Simplify rewrites the tree bottom-up with algebraic identities that keep
+// the language unchanged. Capture groups do not affect the language and are
+// removed. Among others:
+//
+//	r|r → r       ∅|r → r       ε|r → r?       (r*)* → r*
+//	ε·r → r       ∅·r → ∅       r{1} → r       (r?)* → r*
+//	[] → ∅        ∅* → ε        ∅&r → ∅       ~~r → r
+func Simplify(n RegexNode) RegexNode {
+	switch n := n.(type) {
+	case GroupNode:
+		return Simplify(n.Sub)
+
+	case ClassNode:
+		if !n.Negated && len(n.Set.Normalize()) == 0 {
+			return EmptyNode{}
+		}
+		return n
+
+	case ConcatNode:
+		parts := make([]RegexNode, 0, len(n.Parts))
+		for _, part := range n.Parts {
+			part = Simplify(part)
+			switch part.Kind() {
+			case RegexEmpty:
+				return EmptyNode{}
+			case RegexEpsilon:
+				continue
+			case RegexConcat:
+				parts = append(parts, part.(ConcatNode).Parts...)
+			default:
+				parts = append(parts, part)
+			}
+		}
+		switch len(parts) {
+		case 0:
+			return EpsilonNode{}
+		case 1:
+			return parts[0]
+		}
+		return ConcatNode{Parts: parts}
+
+	case AltNode:
+		options := make([]RegexNode, 0, len(n.Options))
+		seen := make(map[string]bool)
+		hasEpsilon := false
+		var add func(option RegexNode)
+		add = func(option RegexNode) {
+			switch option.Kind() {
+			case RegexEmpty:
+				return
+			case RegexEpsilon:
+				hasEpsilon = true
+				return
+			case RegexAlt:
+				for _, o := range option.(AltNode).Options {
+					add(o)
+				}
+				return
+			}
+			if key := option.String(); !seen[key] {
+				seen[key] = true
+				options = append(options, option)
+			}
+		}
+		for _, option := range n.Options {
+			add(Simplify(option))
+		}
+
+		nullable := false
+		for _, option := range options {
+			nullable = nullable || Nullable(option)
+		}
+
+		var result RegexNode
+		switch len(options) {
+		case 0:
+			if hasEpsilon {
+				return EpsilonNode{}
+			}
+			return EmptyNode{}
+		case 1:
+			result = options[0]
+		default:
+			result = AltNode{Options: options}
+		}
+
+		if hasEpsilon && !nullable {
+			return Simplify(OptionalNode{Sub: result})
+		}
+		return result
+
+	case AndNode:
+		options := make([]RegexNode, 0, len(n.Options))
+		seen := make(map[string]bool)
+		for _, option := range n.Options {
+			option = Simplify(option)
+			if option.Kind() == RegexEmpty {
+				return EmptyNode{}
+			}
+			nested := []RegexNode{option}
+			if option.Kind() == RegexAnd {
+				nested = option.(AndNode).Options
+			}
+			for _, o := range nested {
+				if key := o.String(); !seen[key] {
+					seen[key] = true
+					options = append(options, o)
+				}
+			}
+		}
+		if len(options) == 1 {
+			return options[0]
+		}
+		return AndNode{Options: options}
+
+	case NotNode:
+		sub := Simplify(n.Sub)
+		if not, ok := sub.(NotNode); ok {
+			return not.Sub
+		}
+		return NotNode{Sub: sub}
+
+	case StarNode:
+		sub := Simplify(n.Sub)
+		switch sub := sub.(type) {
+		case EmptyNode, EpsilonNode:
+			return EpsilonNode{}
+		case StarNode:
+			return sub
+		case PlusNode:
+			return StarNode{Sub: sub.Sub}
+		case OptionalNode:
+			return StarNode{Sub: sub.Sub}
+		}
+		return StarNode{Sub: sub}
+
+	case PlusNode:
+		sub := Simplify(n.Sub)
+		switch sub := sub.(type) {
+		case EmptyNode, EpsilonNode, StarNode, PlusNode:
+			return sub
+		case OptionalNode:
+			return StarNode{Sub: sub.Sub}
+		}
+		return PlusNode{Sub: sub}
+
+	case OptionalNode:
+		sub := Simplify(n.Sub)
+		switch sub := sub.(type) {
+		case EmptyNode:
+			return EpsilonNode{}
+		case PlusNode:
+			return StarNode{Sub: sub.Sub}
+		}
+		if Nullable(sub) {
+			return sub
+		}
+		return OptionalNode{Sub: sub}
+
+	case RepeatNode:
+		sub := Simplify(n.Sub)
+		switch {
+		case sub.Kind() == RegexEpsilon:
+			return EpsilonNode{}
+		case sub.Kind() == RegexEmpty && n.Min == 0:
+			return EpsilonNode{}
+		case sub.Kind() == RegexEmpty:
+			return EmptyNode{}
+		case n.Min == 0 && n.Max == 0:
+			return EpsilonNode{}
+		case n.Min == 1 && n.Max == 1:
+			return sub
+		case n.Min == 0 && n.Max < 0:
+			return Simplify(StarNode{Sub: sub})
+		case n.Min == 1 && n.Max < 0:
+			return Simplify(PlusNode{Sub: sub})
+		case n.Min == 0 && n.Max == 1:
+			return Simplify(OptionalNode{Sub: sub})
+		}
+		return RepeatNode{Sub: sub, Min: n.Min, Max: n.Max}
+	}
+
+	return n
+}
+
+// ParseRegex parses a pattern into its abstract syntax tree
+func ParseRegex(pattern string) (RegexNode, error) {
+	return NewRegex(pattern).ParseAST()
+}
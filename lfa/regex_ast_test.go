@@ -0,0 +1,90 @@
+package lfa
+
+import (
+	"testing"
+)
+
+func TestRegexASTRoundTrip(t *testing.T) {
+	patterns := []string{
+		"(a|b)(c|d)E+G?",
+		"P(Q|R|S)T(U|V|W|X)*Z+",
+		"1(0|1)*2(3|4){5}36",
+		"(3|4){2,5}a{2,}b{0,3}",
+		`[a-z0-9_]\.[^ab]\*\\`,
+		".(ab)*|()",
+		"((a*)*)?",
+		`\d+\s\W`,
+		"[]|[^]",
+		"~(a|b)&a*|~a*b(c&d)",
+		`a\&\~`,
+	}
+
+	for _, pattern := range patterns {
+		ast, err := ParseRegex(pattern)
+		if err != nil {
+			t.Fatalf("Failed to parse '%s': %v", pattern, err)
+		}
+
+		printed := ast.String()
+		again, err := ParseRegex(printed)
+		if err != nil {
+			t.Fatalf("Failed to reparse '%s' printed from '%s': %v", printed, pattern, err)
+		}
+		if again.String() != printed {
+			t.Fatalf("round trip of '%s' is not stable: '%s' then '%s'", pattern, printed, again.String())
+		}
+
+		original, _ := CreateNFAFromRegex(pattern)
+		reprinted, _ := CreateNFAFromRegex(printed)
+		for _, w := range allWords("ab0_.", 4) {
+			if original.Accept(w) != reprinted.Accept(w) {
+				t.Fatalf("'%s' and its print '%s' disagree on %q", pattern, printed, w)
+			}
+		}
+	}
+}
+
+func TestRegexSimplify(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		want    string
+	}{
+		{"a|a", "a"},
+		{"(a*)*", "a*"},
+		{"()a", "a"},
+		{"a()b", "ab"},
+		{"a|", "a?"},
+		{"a*|", "a*"},
+		{"(a+)*", "a*"},
+		{"(a?)+", "a*"},
+		{"a{1}", "a"},
+		{"a{0,}", "a*"},
+		{"a{1,}", "a+"},
+		{"a{0,1}", "a?"},
+		{"(a|b)|(b|c)", "a|b|c"},
+		{"[]a|b", "b"},
+		{"([])*", "(?:)"},
+		{"(()){3}", "(?:)"},
+		{"(ab)(cd)", "abcd"},
+	}
+
+	for _, tc := range testCases {
+		ast, err := ParseRegex(tc.pattern)
+		if err != nil {
+			t.Fatalf("Failed to parse '%s': %v", tc.pattern, err)
+		}
+
+		simplified := Simplify(ast)
+		if got := simplified.String(); got != tc.want {
+			t.Errorf("Simplify('%s') = '%s', want '%s'", tc.pattern, got, tc.want)
+		}
+
+		original := NewRegex("").Compile(ast)
+		compiled := NewRegex("").Compile(simplified)
+		for _, w := range allWords("abcd", 4) {
+			if original.Accept(w) != compiled.Accept(w) {
+				t.Fatalf("simplifying '%s' changed the language on %q", tc.pattern, w)
+			}
+		}
+	}
+}
@@ -0,0 +1,373 @@
+package lfa
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+type tagKind int
+
+const (
+	tagRune  tagKind = iota // consume one rune of set, then go to out[0]
+	tagSplit                // epsilon moves to every state of out
+	tagSave                 // record the current offset in slot, then go to out[0]
+	tagBegin                // go to out[0] at the start of the input only
+	tagEnd                  // go to out[0] at the end of the input only
+	tagMatch                // accept
+)
+
+// tagState is a state of a TaggedNFA
+type tagState struct {
+	kind tagKind
+	set  Alphabet
+	slot int
+	out  []int
+}
+
+// TaggedNFA is a Thompson NFA whose epsilon moves may carry tags: entering
+// or leaving capture group i records the current byte offset in slot 2i or
+// 2i+1. Slots 0 and 1 hold the bounds of the whole match. It is simulated
+// with a Pike VM, which runs all threads in lockstep and keeps one thread,
+// with its own slots, per state.
+type TaggedNFA struct {
+	states []tagState
+	start  int
+	groups int
+}
+
+// CompileTagged builds the tagged NFA of a regex syntax tree. Intersection
+// and complement have no tagged construction and are reported as errors.
+func (r *Regex) CompileTagged(node RegexNode) (*TaggedNFA, error) {
+	t := &TaggedNFA{groups: countGroups(node)}
+
+	match := t.add(tagState{kind: tagMatch})
+	end := t.add(tagState{kind: tagSave, slot: 1, out: []int{match}})
+	body, err := t.compile(r, node, end)
+	if err != nil {
+		return nil, err
+	}
+	t.start = t.add(tagState{kind: tagSave, slot: 0, out: []int{body}})
+
+	return t, nil
+}
+
+// CompilePattern parses a pattern and builds its tagged NFA
+func CompilePattern(pattern string) (*TaggedNFA, error) {
+	r := NewRegex(pattern)
+	node, err := r.ParseAST()
+	if err != nil {
+		return nil, err
+	}
+	return r.CompileTagged(node)
+}
+
+func countGroups(node RegexNode) int {
+	count := 0
+	var walk func(n RegexNode)
+	walk = func(n RegexNode) {
+		switch n := n.(type) {
+		case GroupNode:
+			if n.Index > count {
+				count = n.Index
+			}
+			walk(n.Sub)
+		case ConcatNode:
+			for _, part := range n.Parts {
+				walk(part)
+			}
+		case AltNode:
+			for _, option := range n.Options {
+				walk(option)
+			}
+		case StarNode:
+			walk(n.Sub)
+		case PlusNode:
+			walk(n.Sub)
+		case OptionalNode:
+			walk(n.Sub)
+		case RepeatNode:
+			walk(n.Sub)
+		}
+	}
+	walk(node)
+	return count
+}
+
+func (t *TaggedNFA) add(s tagState) int {
+	t.states = append(t.states, s)
+	return len(t.states) - 1
+}
+
+// compile builds the states of node in front of the state next and returns
+// the entry state. Building backwards avoids patching dangling edges.
+func (t *TaggedNFA) compile(r *Regex, node RegexNode, next int) (int, error) {
+	switch n := node.(type) {
+	case EmptyNode:
+		return t.add(tagState{kind: tagRune, set: Alphabet{}, out: []int{next}}), nil
+
+	case EpsilonNode:
+		return next, nil
+
+	case LiteralNode:
+		return t.add(tagState{kind: tagRune, set: Alphabet{Sym(n.Char)}, out: []int{next}}), nil
+
+	case ClassNode:
+		return t.add(tagState{kind: tagRune, set: r.resolveClass(n), out: []int{next}}), nil
+
+	case ConcatNode:
+		for i := len(n.Parts) - 1; i >= 0; i-- {
+			entry, err := t.compile(r, n.Parts[i], next)
+			if err != nil {
+				return 0, err
+			}
+			next = entry
+		}
+		return next, nil
+
+	case AltNode:
+		out := make([]int, 0, len(n.Options))
+		for _, option := range n.Options {
+			entry, err := t.compile(r, option, next)
+			if err != nil {
+				return 0, err
+			}
+			out = append(out, entry)
+		}
+		return t.add(tagState{kind: tagSplit, out: out}), nil
+
+	case StarNode:
+		loop := t.add(tagState{kind: tagSplit})
+		body, err := t.compile(r, n.Sub, loop)
+		if err != nil {
+			return 0, err
+		}
+		t.states[loop].out = []int{body, next}
+		return loop, nil
+
+	case PlusNode:
+		loop := t.add(tagState{kind: tagSplit})
+		body, err := t.compile(r, n.Sub, loop)
+		if err != nil {
+			return 0, err
+		}
+		t.states[loop].out = []int{body, next}
+		return body, nil
+
+	case OptionalNode:
+		body, err := t.compile(r, n.Sub, next)
+		if err != nil {
+			return 0, err
+		}
+		return t.add(tagState{kind: tagSplit, out: []int{body, next}}), nil
+
+	case RepeatNode:
+		// Unrolled like RepeatRangeNFA: min copies, then optional copies or
+		// a starred copy
+		parts := make([]RegexNode, 0)
+		for i := 0; i < n.Min; i++ {
+			parts = append(parts, n.Sub)
+		}
+		if n.Max < 0 {
+			parts = append(parts, StarNode{Sub: n.Sub})
+		}
+		for i := n.Min; i < n.Max; i++ {
+			parts = append(parts, OptionalNode{Sub: n.Sub})
+		}
+		return t.compile(r, ConcatNode{Parts: append(parts, EpsilonNode{})}, next)
+
+	case AnchorNode:
+		kind := tagBegin
+		if n.End {
+			kind = tagEnd
+		}
+		return t.add(tagState{kind: kind, out: []int{next}}), nil
+
+	case GroupNode:
+		closeGroup := t.add(tagState{kind: tagSave, slot: 2*n.Index + 1, out: []int{next}})
+		body, err := t.compile(r, n.Sub, closeGroup)
+		if err != nil {
+			return 0, err
+		}
+		return t.add(tagState{kind: tagSave, slot: 2 * n.Index, out: []int{body}}), nil
+	}
+
+	return 0, fmt.Errorf("submatch extraction does not support %s", node)
+}
+
+// NumGroups returns the number of capturing groups
+func (t *TaggedNFA) NumGroups() int {
+	return t.groups
+}
+
+// openEnd stands for the end of a group that is still open
+const openEnd = int(^uint(0) >> 1)
+
+// posixBetter reports whether the slots a describe a better match than b
+// under the POSIX rules: groups are compared in order, and for each one an
+// earlier start wins, then a longer extent. A group that has started but not
+// ended yet is treated as extending to infinity, since both threads share the
+// same future, and an unset group loses against a set one.
+func posixBetter(a, b []int) bool {
+	for i := 0; i+1 < len(a); i += 2 {
+		as, bs := a[i], b[i]
+		if as != bs {
+			if as < 0 {
+				return false
+			}
+			if bs < 0 {
+				return true
+			}
+			return as < bs
+		}
+
+		ae, be := a[i+1], b[i+1]
+		if ae < 0 {
+			ae = openEnd
+		}
+		if be < 0 {
+			be = openEnd
+		}
+		if ae != be {
+			return ae > be
+		}
+	}
+	return false
+}
+
+// threadList holds at most one thread per state
+type threadList struct {
+	order []int
+	slots map[int][]int
+}
+
+func newThreadList() *threadList {
+	return &threadList{slots: make(map[int][]int)}
+}
+
+// addThread follows the epsilon closure of state at offset pos of an input of
+// length end, recording tags and checking anchors along the way. A thread
+// reaching a state that already holds one only replaces it, and is only
+// propagated further, when it is better.
+func (t *TaggedNFA) addThread(list *threadList, state int, slots []int, pos, end int) {
+	if existing, ok := list.slots[state]; ok {
+		if !posixBetter(slots, existing) {
+			return
+		}
+	} else {
+		list.order = append(list.order, state)
+	}
+	list.slots[state] = slots
+
+	s := t.states[state]
+	switch s.kind {
+	case tagSplit:
+		for _, next := range s.out {
+			t.addThread(list, next, slots, pos, end)
+		}
+	case tagBegin:
+		if pos == 0 {
+			t.addThread(list, s.out[0], slots, pos, end)
+		}
+	case tagEnd:
+		if pos == end {
+			t.addThread(list, s.out[0], slots, pos, end)
+		}
+	case tagSave:
+		tagged := append([]int{}, slots...)
+		tagged[s.slot] = pos
+		if s.slot%2 == 0 {
+			// A new iteration of the group forgets the end of the previous one
+			tagged[s.slot+1] = -1
+		}
+		t.addThread(list, s.out[0], tagged, pos, end)
+	}
+}
+
+func (t *TaggedNFA) newSlots() []int {
+	slots := make([]int, 2*(t.groups+1))
+	for i := range slots {
+		slots[i] = -1
+	}
+	return slots
+}
+
+// MatchSubmatch matches the whole input and returns the byte offsets of the
+// match and of every group as pairs: s[m[2i]:m[2i+1]] is the text of group
+// i, and a group that did not participate has -1 offsets. Among the ways of
+// matching, the POSIX one is chosen: every group, from left to right, starts
+// as early and extends as far as possible. The result is nil if s does not
+// match.
+func (t *TaggedNFA) MatchSubmatch(s string) []int {
+	current := newThreadList()
+	t.addThread(current, t.start, t.newSlots(), 0, len(s))
+
+	for pos := 0; pos < len(s); {
+		c, size := utf8.DecodeRuneInString(s[pos:])
+		next := newThreadList()
+		for _, state := range current.order {
+			st := t.states[state]
+			if st.kind == tagRune && st.set.Contains(c) {
+				t.addThread(next, st.out[0], current.slots[state], pos+size, len(s))
+			}
+		}
+		current = next
+		pos += size
+
+		if len(current.order) == 0 {
+			return nil
+		}
+	}
+
+	for _, state := range current.order {
+		if t.states[state].kind == tagMatch {
+			return current.slots[state]
+		}
+	}
+	return nil
+}
+
+// MatchStringSubmatch is like MatchSubmatch but returns the texts of the
+// match and of the groups, with "" for groups that did not participate
+func (t *TaggedNFA) MatchStringSubmatch(s string) []string {
+	m := t.MatchSubmatch(s)
+	if m == nil {
+		return nil
+	}
+
+	texts := make([]string, 0, len(m)/2)
+	for i := 0; i < len(m); i += 2 {
+		if m[i] < 0 {
+			texts = append(texts, "")
+		} else {
+			texts = append(texts, s[m[i]:m[i+1]])
+		}
+	}
+	return texts
+}
+
+// String lists the states of the tagged NFA, one per line
+func (t *TaggedNFA) String() string {
+	var sb strings.Builder
+	for i, s := range t.states {
+		marker := " "
+		if i == t.start {
+			marker = ">"
+		}
+		switch s.kind {
+		case tagRune:
+			fmt.Fprintf(&sb, "%s%3d: %s → %d\n", marker, i, s.set, s.out[0])
+		case tagSplit:
+			fmt.Fprintf(&sb, "%s%3d: ε → %v\n", marker, i, s.out)
+		case tagSave:
+			fmt.Fprintf(&sb, "%s%3d: ε/t%d → %d\n", marker, i, s.slot, s.out[0])
+		case tagBegin:
+			fmt.Fprintf(&sb, "%s%3d: ^ → %d\n", marker, i, s.out[0])
+		case tagEnd:
+			fmt.Fprintf(&sb, "%s%3d: $ → %d\n", marker, i, s.out[0])
+		case tagMatch:
+			fmt.Fprintf(&sb, "%s%3d: match\n", marker, i)
+		}
+	}
+	return sb.String()
+}
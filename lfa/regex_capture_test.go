@@ -0,0 +1,82 @@
+package lfa
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRegexSubmatchPOSIX(t *testing.T) {
+	tests := []struct {
+		pattern string
+		input   string
+		want    []int
+	}{
+		{"(a|ab)(c|bcd)(d*)", "abcd", []int{0, 4, 0, 2, 2, 3, 3, 4}},
+		{"(a*)(a*)", "aaa", []int{0, 3, 0, 3, 3, 3}},
+		{"(a|b)*", "abb", []int{0, 3, 2, 3}},
+		{"(?:a(b))?c", "c", []int{0, 1, -1, -1}},
+		{"(?:a(b))?c", "abc", []int{0, 3, 1, 2}},
+		{"(a*)+", "", []int{0, 0, 0, 0}},
+		{"(a*)+", "aa", []int{0, 2, 0, 2}},
+		{"(a{2})+(b)?", "aaaa", []int{0, 4, 2, 4, -1, -1}},
+		{"x(é+)y", "xééy", []int{0, 6, 1, 5}},
+		{"(a|b)*c", "abb", nil},
+	}
+
+	for _, tt := range tests {
+		program, err := CompilePattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("Failed to compile '%s': %v", tt.pattern, err)
+		}
+		if got := program.MatchSubmatch(tt.input); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("'%s' on %q: got %v, expected %v", tt.pattern, tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestRegexSubmatchFields(t *testing.T) {
+	program, err := CompilePattern(`(\d+)-(\d+)(?:-(\w+))?`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if program.NumGroups() != 3 {
+		t.Fatalf("expected 3 groups, got %d", program.NumGroups())
+	}
+
+	got := program.MatchStringSubmatch("12-345")
+	want := []string{"12-345", "12", "345", ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, expected %q", got, want)
+	}
+
+	got = program.MatchStringSubmatch("12-345-ab_c")
+	want = []string{"12-345-ab_c", "12", "345", "ab_c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, expected %q", got, want)
+	}
+}
+
+func TestRegexTaggedAgreesWithNFA(t *testing.T) {
+	patterns := []string{"(a|b)*abb", "(a(b)?)+", "((a)|b){2,3}", "(?:ab|a)(b*)", "a()b"}
+
+	for _, pattern := range patterns {
+		nfa, err := CreateNFAFromRegex(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		program, err := CompilePattern(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, w := range allWords("ab", 6) {
+			if got := program.MatchSubmatch(w) != nil; got != nfa.Accept(w) {
+				t.Fatalf("'%s' on %q: tagged NFA says %v, NFA says %v", pattern, w, got, nfa.Accept(w))
+			}
+		}
+	}
+
+	if _, err := CompilePattern("a&b"); err == nil {
+		t.Error("expected an error for an intersection")
+	}
+}
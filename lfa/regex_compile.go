@@ -0,0 +1,197 @@
+package lfa
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// EpsilonEdge is an ε-transition added by a construction step
+type EpsilonEdge struct {
+	From, To State
+}
+
+func (e EpsilonEdge) String() string {
+	return fmt.Sprintf("%s→%s", e.From, e.To)
+}
+
+// ThompsonStep records one helper call made by Compile: which construction
+// ran, on which sub-pattern, the states it created and the epsilon edges it
+// added to the automata it was given
+type ThompsonStep struct {
+	Helper       string
+	Pattern      string
+	NewStates    []State
+	EpsilonEdges []EpsilonEdge
+	// NFA is a snapshot of the automaton built by the step
+	NFA *NFA
+}
+
+func (s ThompsonStep) String() string {
+	states := "no new states"
+	if len(s.NewStates) > 0 {
+		states = fmt.Sprintf("%d new states (%s)", len(s.NewStates), strings.Join(s.NewStates, ", "))
+	}
+	edges := "no ε-edges added"
+	if len(s.EpsilonEdges) > 0 {
+		names := make([]string, 0, len(s.EpsilonEdges))
+		for _, e := range s.EpsilonEdges {
+			names = append(names, e.String())
+		}
+		edges = fmt.Sprintf("%d ε-edges added (%s)", len(s.EpsilonEdges), strings.Join(names, ", "))
+	}
+	return fmt.Sprintf("%s on %s: %s, %s", s.Helper, s.Pattern, states, edges)
+}
+
+// Steps returns the construction steps recorded since the last ParseAST, in
+// the order they ran
+func (r *Regex) Steps() []ThompsonStep {
+	return r.steps
+}
+
+// epsilonEdges lists the ε-transitions of n, ordered by the position of
+// their states in n.Q
+func epsilonEdges(n *NFA) []EpsilonEdge {
+	position := make(map[State]int, len(n.Q))
+	for i, q := range n.Q {
+		position[q] = i
+	}
+
+	edges := make([]EpsilonEdge, 0)
+	for _, from := range n.Q {
+		targets := make([]State, 0, len(n.Delta[from][Epsilon]))
+		for to := range n.Delta[from][Epsilon] {
+			targets = append(targets, to)
+		}
+		sort.Slice(targets, func(i, j int) bool { return position[targets[i]] < position[targets[j]] })
+		for _, to := range targets {
+			edges = append(edges, EpsilonEdge{from, to})
+		}
+	}
+	return edges
+}
+
+// record logs that helper built result for node from the given inputs
+func (r *Regex) record(helper string, node RegexNode, result *NFA, inputs ...*NFA) *NFA {
+	old := make(map[State]bool)
+	oldEdges := make(map[EpsilonEdge]bool)
+	for _, in := range inputs {
+		for _, q := range in.Q {
+			old[q] = true
+		}
+		for _, e := range epsilonEdges(in) {
+			oldEdges[e] = true
+		}
+	}
+
+	created := make([]State, 0)
+	for _, q := range result.Q {
+		if !old[q] {
+			created = append(created, q)
+		}
+	}
+	added := make([]EpsilonEdge, 0)
+	for _, e := range epsilonEdges(result) {
+		if !oldEdges[e] {
+			added = append(added, e)
+		}
+	}
+
+	r.steps = append(r.steps, ThompsonStep{
+		Helper:       helper,
+		Pattern:      node.String(),
+		NewStates:    created,
+		EpsilonEdges: added,
+		NFA:          deepCopyNFA(result),
+	})
+	return result
+}
+
+// Compile builds an NFA from a regex syntax tree with Thompson's
+// construction. Negated classes and the wildcard are resolved against the
+// regex alphabet, and states are named from the regex state counter, so
+// several compilations with the same Regex never share state names. Every
+// helper call is recorded, see Steps. Anchors are read as the empty string,
+// so node should pass CheckAnchors, as it does in Parse.
+func (r *Regex) Compile(node RegexNode) *NFA {
+	prefix, counter := r.statePrefix, &r.stateCounter
+
+	switch n := node.(type) {
+	case EmptyNode:
+		// Two states and no edge: nothing is accepted
+		return r.record("CreateWildcardNFA", n, CreateWildcardNFA(Alphabet{}, prefix, counter))
+
+	case EpsilonNode, AnchorNode:
+		return r.record("CreateEmptyNFA", n, CreateEmptyNFA(prefix, counter))
+
+	case LiteralNode:
+		return r.record("CreateBasicNFA", n, CreateBasicNFA(n.Char, prefix, counter))
+
+	case ClassNode:
+		return r.record("CreateWildcardNFA", n, CreateWildcardNFA(r.resolveClass(n), prefix, counter))
+
+	case ConcatNode:
+		nfa := r.Compile(n.Parts[0])
+		for i, part := range n.Parts[1:] {
+			next := r.Compile(part)
+			nfa = r.record("ConcatenateNFAs", ConcatNode{Parts: n.Parts[:i+2]}, ConcatenateNFAs(nfa, next), nfa, next)
+		}
+		return nfa
+
+	case AltNode:
+		nfa := r.Compile(n.Options[0])
+		for i, option := range n.Options[1:] {
+			next := r.Compile(option)
+			nfa = r.record("UnionNFAs", AltNode{Options: n.Options[:i+2]}, UnionNFAs(nfa, next, prefix, counter), nfa, next)
+		}
+		return nfa
+
+	case StarNode:
+		sub := r.Compile(n.Sub)
+		return r.record("StarNFA", n, StarNFA(sub, prefix, counter), sub)
+
+	case PlusNode:
+		sub := r.Compile(n.Sub)
+		return r.record("PlusNFA", n, PlusNFA(sub, prefix, counter), sub)
+
+	case OptionalNode:
+		sub := r.Compile(n.Sub)
+		return r.record("QuestionNFA", n, QuestionNFA(sub, prefix, counter), sub)
+
+	case GroupNode:
+		// Groups only matter for submatch extraction, see CompileTagged
+		return r.Compile(n.Sub)
+
+	case RepeatNode:
+		sub := r.Compile(n.Sub)
+		return r.record("RepeatRangeNFA", n, RepeatRangeNFA(sub, n.Min, n.Max, prefix, counter), sub)
+
+	case AndNode:
+		// Thompson's construction has no rule for intersection, so the
+		// operands are determinized and combined with the product automaton
+		inputs := []*NFA{r.Compile(n.Options[0])}
+		dfa := inputs[0].ToDFA()
+		for _, option := range n.Options[1:] {
+			next := r.Compile(option)
+			inputs = append(inputs, next)
+			dfa = IntersectDFA(dfa, next.ToDFA())
+		}
+		return r.record("IntersectDFA", n, renameNFA(dfa.ToNFA(), prefix, counter), inputs...)
+
+	case NotNode:
+		// Complementing needs a complete DFA over the alphabet
+		sub := r.Compile(n.Sub)
+		dfa := sub.ToDFA().Complement(r.alphabet)
+		return r.record("Complement", n, renameNFA(dfa.ToNFA(), prefix, counter), sub)
+	}
+
+	panic("lfa: unknown regex node")
+}
+
+// resolveClass returns the runes a class matches under the regex alphabet
+func (r *Regex) resolveClass(n ClassNode) Alphabet {
+	if n.Negated {
+		return n.Set.Complement(r.alphabet)
+	}
+	return n.Set.Normalize()
+}
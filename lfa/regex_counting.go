@@ -0,0 +1,305 @@
+package lfa
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type countKind int
+
+const (
+	countRune  countKind = iota // consume one rune of set, then go to out
+	countSplit                  // epsilon moves to every state of alts
+	countReset                  // set the counter to 0, then go to out
+	countCheck                  // go to body while below max, to out once min is reached
+	countIncr                   // increment the counter, then go to out
+	countMatch                  // accept
+)
+
+// countState is a state of a CountingNFA
+type countState struct {
+	kind     countKind
+	set      Alphabet
+	counter  int
+	min, max int
+	body     int
+	out      int
+	alts     []int
+}
+
+// CountingNFA is a Thompson NFA extended with counters, so that a bounded
+// repetition r{m,n} is built from a single copy of r instead of n. Entering
+// the repetition resets its counter, every pass through r increments it, and
+// the loop may only be taken again below n and left from m on. It is
+// simulated on configurations made of a state and the values of the
+// counters; an unbounded maximum saturates the counter at m, which keeps the
+// configurations finite.
+type CountingNFA struct {
+	states   []countState
+	start    int
+	counters int
+
+	regex *Regex
+	node  RegexNode
+}
+
+// CompileCounting builds the counting NFA of a regex syntax tree. Groups are
+// ignored as in Compile, while misplaced anchors (see CheckAnchors),
+// intersection and complement are reported as errors.
+func (r *Regex) CompileCounting(node RegexNode) (*CountingNFA, error) {
+	if err := CheckAnchors(node); err != nil {
+		return nil, err
+	}
+	c := &CountingNFA{regex: r, node: node}
+
+	match := c.add(countState{kind: countMatch})
+	start, err := c.compile(r, node, match)
+	if err != nil {
+		return nil, err
+	}
+	c.start = start
+
+	return c, nil
+}
+
+// CompileCountingPattern parses a pattern and builds its counting NFA
+func CompileCountingPattern(pattern string) (*CountingNFA, error) {
+	r := NewRegex(pattern)
+	node, err := r.ParseAST()
+	if err != nil {
+		return nil, err
+	}
+	return r.CompileCounting(node)
+}
+
+func (c *CountingNFA) add(s countState) int {
+	c.states = append(c.states, s)
+	return len(c.states) - 1
+}
+
+// compile builds the states of node in front of the state next and returns
+// the entry state, like TaggedNFA.compile
+func (c *CountingNFA) compile(r *Regex, node RegexNode, next int) (int, error) {
+	switch n := node.(type) {
+	case EmptyNode:
+		return c.add(countState{kind: countRune, set: Alphabet{}, out: next}), nil
+
+	case EpsilonNode, AnchorNode:
+		return next, nil
+
+	case LiteralNode:
+		return c.add(countState{kind: countRune, set: Alphabet{Sym(n.Char)}, out: next}), nil
+
+	case ClassNode:
+		return c.add(countState{kind: countRune, set: r.resolveClass(n), out: next}), nil
+
+	case GroupNode:
+		return c.compile(r, n.Sub, next)
+
+	case ConcatNode:
+		for i := len(n.Parts) - 1; i >= 0; i-- {
+			entry, err := c.compile(r, n.Parts[i], next)
+			if err != nil {
+				return 0, err
+			}
+			next = entry
+		}
+		return next, nil
+
+	case AltNode:
+		alts := make([]int, 0, len(n.Options))
+		for _, option := range n.Options {
+			entry, err := c.compile(r, option, next)
+			if err != nil {
+				return 0, err
+			}
+			alts = append(alts, entry)
+		}
+		return c.add(countState{kind: countSplit, alts: alts}), nil
+
+	case StarNode:
+		loop := c.add(countState{kind: countSplit})
+		body, err := c.compile(r, n.Sub, loop)
+		if err != nil {
+			return 0, err
+		}
+		c.states[loop].alts = []int{body, next}
+		return loop, nil
+
+	case PlusNode:
+		loop := c.add(countState{kind: countSplit})
+		body, err := c.compile(r, n.Sub, loop)
+		if err != nil {
+			return 0, err
+		}
+		c.states[loop].alts = []int{body, next}
+		return body, nil
+
+	case OptionalNode:
+		body, err := c.compile(r, n.Sub, next)
+		if err != nil {
+			return 0, err
+		}
+		return c.add(countState{kind: countSplit, alts: []int{body, next}}), nil
+
+	case RepeatNode:
+		counter := c.counters
+		c.counters++
+
+		check := c.add(countState{kind: countCheck, counter: counter, min: n.Min, max: n.Max, out: next})
+		incr := c.add(countState{kind: countIncr, counter: counter, min: n.Min, max: n.Max, out: check})
+		body, err := c.compile(r, n.Sub, incr)
+		if err != nil {
+			return 0, err
+		}
+		c.states[check].body = body
+		return c.add(countState{kind: countReset, counter: counter, out: check}), nil
+	}
+
+	return 0, fmt.Errorf("counting automata do not support %s", node)
+}
+
+// NumStates returns the number of states of the counting NFA
+func (c *CountingNFA) NumStates() int {
+	return len(c.states)
+}
+
+// NumCounters returns the number of counters, one per bounded repetition
+func (c *CountingNFA) NumCounters() int {
+	return c.counters
+}
+
+// configs is a set of configurations in insertion order
+type configs struct {
+	order  []int
+	values [][]int
+	seen   map[string]bool
+}
+
+func newConfigs() *configs {
+	return &configs{seen: make(map[string]bool)}
+}
+
+// configKey identifies a state together with the counter values
+func configKey(state int, values []int) string {
+	var sb strings.Builder
+	sb.WriteString(strconv.Itoa(state))
+	for _, v := range values {
+		sb.WriteByte(',')
+		sb.WriteString(strconv.Itoa(v))
+	}
+	return sb.String()
+}
+
+// addConfig adds the configuration and follows its epsilon closure
+func (c *CountingNFA) addConfig(list *configs, state int, values []int) {
+	key := configKey(state, values)
+	if list.seen[key] {
+		return
+	}
+	list.seen[key] = true
+	list.order = append(list.order, state)
+	list.values = append(list.values, values)
+
+	s := c.states[state]
+	switch s.kind {
+	case countSplit:
+		for _, next := range s.alts {
+			c.addConfig(list, next, values)
+		}
+
+	case countReset:
+		reset := append([]int{}, values...)
+		reset[s.counter] = 0
+		c.addConfig(list, s.out, reset)
+
+	case countCheck:
+		v := values[s.counter]
+		if s.max < 0 || v < s.max {
+			c.addConfig(list, s.body, values)
+		}
+		if v >= s.min {
+			c.addConfig(list, s.out, values)
+		}
+
+	case countIncr:
+		incr := append([]int{}, values...)
+		incr[s.counter]++
+		// Beyond the minimum an unbounded repetition only needs to know
+		// that the minimum was reached
+		if s.max < 0 && incr[s.counter] > s.min {
+			incr[s.counter] = s.min
+		}
+		c.addConfig(list, s.out, incr)
+	}
+}
+
+// Accept reports whether the whole input matches, by simulating the
+// configurations of the counting NFA in lockstep
+func (c *CountingNFA) Accept(s string) bool {
+	current := newConfigs()
+	c.addConfig(current, c.start, make([]int, c.counters))
+
+	for pos := 0; pos < len(s); {
+		char, size := utf8.DecodeRuneInString(s[pos:])
+		next := newConfigs()
+		for i, state := range current.order {
+			st := c.states[state]
+			if st.kind == countRune && st.set.Contains(char) {
+				c.addConfig(next, st.out, current.values[i])
+			}
+		}
+		current = next
+		pos += size
+
+		if len(current.order) == 0 {
+			return false
+		}
+	}
+
+	for _, state := range current.order {
+		if c.states[state].kind == countMatch {
+			return true
+		}
+	}
+	return false
+}
+
+// Unroll builds the equivalent plain NFA, where every bounded repetition is
+// expanded into copies of its body by Thompson's construction. Its size
+// grows with the bounds, so it is only built when asked for.
+func (c *CountingNFA) Unroll() *NFA {
+	return c.regex.Compile(c.node)
+}
+
+// String lists the states of the counting NFA, one per line
+func (c *CountingNFA) String() string {
+	var sb strings.Builder
+	for i, s := range c.states {
+		marker := " "
+		if i == c.start {
+			marker = ">"
+		}
+		switch s.kind {
+		case countRune:
+			fmt.Fprintf(&sb, "%s%3d: %s → %d\n", marker, i, s.set, s.out)
+		case countSplit:
+			fmt.Fprintf(&sb, "%s%3d: ε → %v\n", marker, i, s.alts)
+		case countReset:
+			fmt.Fprintf(&sb, "%s%3d: c%d := 0 → %d\n", marker, i, s.counter, s.out)
+		case countCheck:
+			bound := "∞"
+			if s.max >= 0 {
+				bound = strconv.Itoa(s.max)
+			}
+			fmt.Fprintf(&sb, "%s%3d: c%d < %s → %d, c%d ≥ %d → %d\n", marker, i, s.counter, bound, s.body, s.counter, s.min, s.out)
+		case countIncr:
+			fmt.Fprintf(&sb, "%s%3d: c%d++ → %d\n", marker, i, s.counter, s.out)
+		case countMatch:
+			fmt.Fprintf(&sb, "%s%3d: match\n", marker, i)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,72 @@
+package lfa
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCountingNFAMatchesThompson(t *testing.T) {
+	patterns := []string{
+		"a{3}",
+		"(a|b){2,4}",
+		"a{2,}b",
+		"a{,2}b",
+		"(a?){2,3}",
+		"(a*b){0}a",
+		"((ab){1,2}b){2}",
+		"(a{2}|b){1,3}",
+		"[ab]{2}a*(b|)",
+		"(()){3}",
+	}
+
+	for _, pattern := range patterns {
+		counting, err := CompileCountingPattern(pattern)
+		if err != nil {
+			t.Fatalf("'%s': %v", pattern, err)
+		}
+		thompson := counting.Unroll()
+
+		for _, w := range allWords("ab", 8) {
+			if got, want := counting.Accept(w), thompson.Accept(w); got != want {
+				t.Fatalf("counting NFA of '%s' on %q = %v, Thompson NFA says %v", pattern, w, got, want)
+			}
+		}
+	}
+}
+
+func TestCountingNFALargeBounds(t *testing.T) {
+	counting, err := CompileCountingPattern("(a|b){1000}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A single copy of the body, whatever the bound
+	if counting.NumStates() > 10 || counting.NumCounters() != 1 {
+		t.Fatalf("expected a small automaton with one counter, got %d states:\n%s", counting.NumStates(), counting)
+	}
+
+	word := strings.Repeat("ab", 500)
+	assert(t, counting.Accept(word), "should accept 1000 symbols")
+	assert(t, !counting.Accept(word[1:]), "should reject 999 symbols")
+	assert(t, !counting.Accept(word+"a"), "should reject 1001 symbols")
+	assert(t, !counting.Accept(word[:999]+"c"), "should reject other symbols")
+
+	// Counters of unbounded and nested repetitions stay small
+	counting, err = CompileCountingPattern("(a{500,}b){2,}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := strings.Repeat("a", 600) + "b"
+	assert(t, counting.Accept(strings.Repeat(block, 3)), "should accept three blocks")
+	assert(t, !counting.Accept(block), "should reject a single block")
+	assert(t, !counting.Accept(strings.Repeat("a", 499)+"b"+block), "should reject a short block")
+
+	if counting.NumCounters() != 2 {
+		t.Errorf("expected 2 counters, got %d", counting.NumCounters())
+	}
+}
+
+func TestCountingNFAUnsupported(t *testing.T) {
+	if _, err := CompileCountingPattern("a{2}&b"); err == nil {
+		t.Error("intersection should be reported as unsupported")
+	}
+}
@@ -0,0 +1,365 @@
+package lfa
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Brzozowski derivatives: the derivative of a language L by a rune c is
+// { w | cw ∈ L }. A word is matched by deriving the expression by each of its
+// runes in turn and checking that the result is nullable. Building every
+// derivative up to similarity gives a DFA whose states are expressions.
+//
+// Similarity is enforced by the smart constructors below, which keep
+// alternations and intersections flattened, sorted and free of duplicates.
+// With these identities a regex has finitely many distinct derivatives.
+
+// universal matches every word over the regex alphabet
+var universal RegexNode = NotNode{Sub: EmptyNode{}}
+
+func isUniversal(n RegexNode) bool {
+	not, ok := n.(NotNode)
+	return ok && not.Sub.Kind() == RegexEmpty
+}
+
+// mkConcat builds a·b
+func mkConcat(a, b RegexNode) RegexNode {
+	switch {
+	case a.Kind() == RegexEmpty || b.Kind() == RegexEmpty:
+		return EmptyNode{}
+	case a.Kind() == RegexEpsilon:
+		return b
+	case b.Kind() == RegexEpsilon:
+		return a
+	}
+
+	parts := make([]RegexNode, 0)
+	for _, n := range []RegexNode{a, b} {
+		if concat, ok := n.(ConcatNode); ok {
+			parts = append(parts, concat.Parts...)
+		} else {
+			parts = append(parts, n)
+		}
+	}
+	return ConcatNode{Parts: parts}
+}
+
+// mkSet collects the operands of an alternation or intersection, flattening
+// nested nodes of the same kind and dropping duplicates, in a canonical order
+func mkSet(kind RegexKind, operands []RegexNode) []RegexNode {
+	byKey := make(map[string]RegexNode)
+	var add func(n RegexNode)
+	add = func(n RegexNode) {
+		switch {
+		case kind == RegexAlt && n.Kind() == RegexAlt:
+			for _, o := range n.(AltNode).Options {
+				add(o)
+			}
+		case kind == RegexAnd && n.Kind() == RegexAnd:
+			for _, o := range n.(AndNode).Options {
+				add(o)
+			}
+		default:
+			byKey[n.String()] = n
+		}
+	}
+	for _, n := range operands {
+		add(n)
+	}
+
+	keys := make([]string, 0, len(byKey))
+	for k := range byKey {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	result := make([]RegexNode, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, byKey[k])
+	}
+	return result
+}
+
+// mkAlt builds r1|r2|…, where ∅ is the neutral and ~∅ the absorbing element
+func mkAlt(options ...RegexNode) RegexNode {
+	kept := make([]RegexNode, 0, len(options))
+	for _, o := range mkSet(RegexAlt, options) {
+		if isUniversal(o) {
+			return universal
+		}
+		if o.Kind() != RegexEmpty {
+			kept = append(kept, o)
+		}
+	}
+
+	switch len(kept) {
+	case 0:
+		return EmptyNode{}
+	case 1:
+		return kept[0]
+	}
+	return AltNode{Options: kept}
+}
+
+// mkAnd builds r1&r2&…, where ~∅ is the neutral and ∅ the absorbing element
+func mkAnd(options ...RegexNode) RegexNode {
+	kept := make([]RegexNode, 0, len(options))
+	for _, o := range mkSet(RegexAnd, options) {
+		if o.Kind() == RegexEmpty {
+			return EmptyNode{}
+		}
+		if !isUniversal(o) {
+			kept = append(kept, o)
+		}
+	}
+
+	switch len(kept) {
+	case 0:
+		return universal
+	case 1:
+		return kept[0]
+	}
+	return AndNode{Options: kept}
+}
+
+// mkStar builds r*
+func mkStar(sub RegexNode) RegexNode {
+	switch sub.Kind() {
+	case RegexEmpty, RegexEpsilon:
+		return EpsilonNode{}
+	case RegexStar:
+		return sub
+	}
+	return StarNode{Sub: sub}
+}
+
+// mkNot builds ~r
+func mkNot(sub RegexNode) RegexNode {
+	if not, ok := sub.(NotNode); ok {
+		return not.Sub
+	}
+	return NotNode{Sub: sub}
+}
+
+// mkRepeat builds r{min,max}, with max < 0 meaning unbounded
+func mkRepeat(sub RegexNode, min, max int) RegexNode {
+	switch {
+	case max == 0:
+		return EpsilonNode{}
+	case min == 0 && max < 0:
+		return mkStar(sub)
+	case min == 1 && max == 1:
+		return sub
+	case sub.Kind() == RegexEmpty && min == 0:
+		return EpsilonNode{}
+	case sub.Kind() == RegexEmpty || sub.Kind() == RegexEpsilon:
+		return sub
+	}
+	return RepeatNode{Sub: sub, Min: min, Max: max}
+}
+
+// Derivative returns the derivative of node by the rune c. Negated classes
+// and complements are taken relative to the regex alphabet.
+func (r *Regex) Derivative(node RegexNode, c rune) RegexNode {
+	switch n := node.(type) {
+	case EmptyNode, EpsilonNode, AnchorNode:
+		return EmptyNode{}
+
+	case LiteralNode:
+		if n.Char == c {
+			return EpsilonNode{}
+		}
+		return EmptyNode{}
+
+	case ClassNode:
+		if r.resolveClass(n).Contains(c) {
+			return EpsilonNode{}
+		}
+		return EmptyNode{}
+
+	case ConcatNode:
+		head := n.Parts[0]
+		var tail RegexNode = EpsilonNode{}
+		if len(n.Parts) > 1 {
+			tail = ConcatNode{Parts: n.Parts[1:]}
+			if len(n.Parts) == 2 {
+				tail = n.Parts[1]
+			}
+		}
+		d := mkConcat(r.Derivative(head, c), tail)
+		if Nullable(head) {
+			return mkAlt(d, r.Derivative(tail, c))
+		}
+		return d
+
+	case AltNode:
+		options := make([]RegexNode, 0, len(n.Options))
+		for _, option := range n.Options {
+			options = append(options, r.Derivative(option, c))
+		}
+		return mkAlt(options...)
+
+	case AndNode:
+		options := make([]RegexNode, 0, len(n.Options))
+		for _, option := range n.Options {
+			options = append(options, r.Derivative(option, c))
+		}
+		return mkAnd(options...)
+
+	case NotNode:
+		// Words outside the alphabet are not in the complement either
+		if !r.alphabet.Contains(c) {
+			return EmptyNode{}
+		}
+		return mkNot(r.Derivative(n.Sub, c))
+
+	case StarNode:
+		return mkConcat(r.Derivative(n.Sub, c), mkStar(n.Sub))
+
+	case PlusNode:
+		return mkConcat(r.Derivative(n.Sub, c), mkStar(n.Sub))
+
+	case OptionalNode:
+		return r.Derivative(n.Sub, c)
+
+	case GroupNode:
+		return r.Derivative(n.Sub, c)
+
+	case RepeatNode:
+		min, max := n.Min-1, n.Max-1
+		if min < 0 {
+			min = 0
+		}
+		if n.Max < 0 {
+			max = -1
+		}
+		return mkConcat(r.Derivative(n.Sub, c), mkRepeat(n.Sub, min, max))
+	}
+
+	panic("lfa: unknown regex node")
+}
+
+// Match checks whether the whole word matches the expression by taking
+// successive derivatives, without building any automaton
+func (r *Regex) Match(word string) (bool, error) {
+	node, err := r.ParseAST()
+	if err != nil {
+		return false, err
+	}
+	if err := CheckAnchors(node); err != nil {
+		return false, err
+	}
+	return r.MatchNode(node, word), nil
+}
+
+// MatchNode checks whether the whole word matches an already parsed
+// expression. Anchors are read as the empty string, so node should pass
+// CheckAnchors.
+func (r *Regex) MatchNode(node RegexNode, word string) bool {
+	node = Simplify(node)
+	for _, c := range word {
+		node = r.Derivative(node, c)
+		if node.Kind() == RegexEmpty {
+			return false
+		}
+	}
+	return Nullable(node)
+}
+
+// collectRanges gathers the ranges tested by the literals and classes of node
+func (r *Regex) collectRanges(node RegexNode, ranges []RuneRange) []RuneRange {
+	switch n := node.(type) {
+	case LiteralNode:
+		return append(ranges, Sym(n.Char))
+	case ClassNode:
+		return append(ranges, r.resolveClass(n)...)
+	case ConcatNode:
+		for _, part := range n.Parts {
+			ranges = r.collectRanges(part, ranges)
+		}
+	case AltNode:
+		for _, option := range n.Options {
+			ranges = r.collectRanges(option, ranges)
+		}
+	case AndNode:
+		for _, option := range n.Options {
+			ranges = r.collectRanges(option, ranges)
+		}
+	case NotNode:
+		ranges = append(ranges, r.alphabet...)
+		return r.collectRanges(n.Sub, ranges)
+	case StarNode:
+		return r.collectRanges(n.Sub, ranges)
+	case PlusNode:
+		return r.collectRanges(n.Sub, ranges)
+	case OptionalNode:
+		return r.collectRanges(n.Sub, ranges)
+	case RepeatNode:
+		return r.collectRanges(n.Sub, ranges)
+	case GroupNode:
+		return r.collectRanges(n.Sub, ranges)
+	}
+	return ranges
+}
+
+// CompileDFA builds a DFA from a regex syntax tree with Brzozowski's
+// construction: states are the distinct derivatives of the expression and
+// the transition from e on c leads to the derivative of e by c. The alphabet
+// is split into ranges on which every literal and class of the expression
+// gives the same answer, so one derivative per range suffices. As with
+// ToDFA the result is partial: the empty language gets no state. Anchors are
+// read as the empty string, so node should pass CheckAnchors.
+func (r *Regex) CompileDFA(node RegexNode) *DFA {
+	sigma := Alphabet(splitRanges(r.withDeclaredAlphabet(r.collectRanges(node, nil))))
+
+	names := make(map[string]State)
+	states := make([]State, 0)
+	final := make([]State, 0)
+	delta := make(DeltaDFA)
+	queue := make([]RegexNode, 0)
+
+	visit := func(n RegexNode) State {
+		key := n.String()
+		if name, ok := names[key]; ok {
+			return name
+		}
+		name := fmt.Sprintf("%s%d", r.statePrefix, r.stateCounter)
+		r.stateCounter++
+		names[key] = name
+		states = append(states, name)
+		if Nullable(n) {
+			final = append(final, name)
+		}
+		queue = append(queue, n)
+		return name
+	}
+
+	start := visit(Simplify(node))
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		from := names[n.String()]
+
+		for _, symbol := range sigma {
+			d := r.Derivative(n, symbol.Lo)
+			if d.Kind() == RegexEmpty {
+				continue
+			}
+			delta.Add(from, symbol, visit(d))
+		}
+	}
+
+	return NewDFA(states, sigma, delta, start, final)
+}
+
+// DerivativeDFA parses the expression and builds its DFA with CompileDFA
+func (r *Regex) DerivativeDFA() (*DFA, error) {
+	node, err := r.ParseAST()
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckAnchors(node); err != nil {
+		return nil, err
+	}
+	return r.CompileDFA(node), nil
+}
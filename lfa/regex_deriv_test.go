@@ -0,0 +1,89 @@
+package lfa
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegexDerivativesAgreeWithThompson(t *testing.T) {
+	patterns := []string{
+		"(a|b)*abb",
+		"a+b?(ab)*",
+		"(a|ab)(b|)",
+		"[ab]{2,3}b*",
+		"a{2,}b",
+		".a.",
+		"()",
+		"[]a",
+		"((a*)*|b)+",
+	}
+
+	for _, pattern := range patterns {
+		nfa, err := CreateNFAFromRegex(pattern)
+		if err != nil {
+			t.Fatalf("Failed to create NFA from regex '%s': %v", pattern, err)
+		}
+
+		r := NewRegex(pattern)
+		r.SetAlphabet(NewAlphabet('a', 'b'))
+		dfa, err := r.DerivativeDFA()
+		if err != nil {
+			t.Fatalf("Failed to build derivative DFA for '%s': %v", pattern, err)
+		}
+
+		for _, w := range allWords("ab", 7) {
+			want := nfa.Accept(w)
+			got, err := r.Match(w)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Fatalf("Match('%s', %q) = %v, Thompson NFA says %v", pattern, w, got, want)
+			}
+			if dfa.Accept(w) != want {
+				t.Fatalf("derivative DFA of '%s' on %q = %v, Thompson NFA says %v", pattern, w, dfa.Accept(w), want)
+			}
+		}
+	}
+}
+
+func TestRegexDerivativeDFAIsSmall(t *testing.T) {
+	r := NewRegex("(a|b)*abb")
+	r.SetAlphabet(NewAlphabet('a', 'b'))
+	dfa, err := r.DerivativeDFA()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The minimal DFA of (a|b)*abb has 4 states
+	if len(dfa.Q) != 4 {
+		t.Errorf("expected 4 derivative states, got %d: %v", len(dfa.Q), dfa.Q)
+	}
+}
+
+func TestRegexDerivativeIntersectionComplement(t *testing.T) {
+	r := NewRegex("")
+	r.SetAlphabet(NewAlphabet('a', 'b'))
+
+	ab, _ := ParseRegex("(a|b)*")
+	aa, _ := ParseRegex(".*aa.*")
+	even, _ := ParseRegex("((a|b)(a|b))*")
+
+	// No two consecutive a's, and of even length
+	node := AndNode{Options: []RegexNode{ab, NotNode{Sub: aa}, even}}
+	dfa := r.CompileDFA(node)
+	nfa := r.Compile(node)
+
+	for _, w := range allWords("abc", 6) {
+		want := !strings.Contains(w, "c") && !strings.Contains(w, "aa") && len(w)%2 == 0
+		if got := r.MatchNode(node, w); got != want {
+			t.Fatalf("MatchNode(%q) = %v, expected %v", w, got, want)
+		}
+		if got := dfa.Accept(w); got != want {
+			t.Fatalf("derivative DFA on %q = %v, expected %v", w, got, want)
+		}
+		if got := nfa.Accept(w); got != want {
+			t.Fatalf("compiled NFA on %q = %v, expected %v", w, got, want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package lfa
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// RegexError describes a syntax error in a pattern: what the parser expected
+// at byte Offset of Pattern and what it found there instead
+type RegexError struct {
+	Pattern  string
+	Offset   int
+	Expected string
+	Found    string
+}
+
+func (e *RegexError) Error() string {
+	return fmt.Sprintf("regex error at offset %d: expected %s, found %s", e.Offset, e.Expected, e.Found)
+}
+
+// Snippet renders the pattern with a caret under the offending position:
+//
+//	a(b|c
+//	     ^ expected ')', found end of pattern
+func (e *RegexError) Snippet() string {
+	// The caret is aligned on runes, not bytes
+	column := utf8.RuneCountInString(e.Pattern[:e.Offset])
+	return fmt.Sprintf("%s\n%s^ expected %s, found %s", e.Pattern, strings.Repeat(" ", column), e.Expected, e.Found)
+}
+
+// describeAt describes what the pattern holds at offset, for the Found part
+// of an error
+func describeAt(pattern string, offset int) string {
+	if offset >= len(pattern) {
+		return "end of pattern"
+	}
+	c, _ := utf8.DecodeRuneInString(pattern[offset:])
+	return fmt.Sprintf("%q", c)
+}
+
+// errorAt reports that expected was not found at offset
+func (r *Regex) errorAt(offset int, expected string) *RegexError {
+	return r.errorFound(offset, expected, describeAt(r.expression, offset))
+}
+
+// errorFound reports that expected was not found at offset, with an explicit
+// description of what was found instead
+func (r *Regex) errorFound(offset int, expected, found string) *RegexError {
+	return &RegexError{Pattern: r.expression, Offset: offset, Expected: expected, Found: found}
+}
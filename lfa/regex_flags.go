@@ -0,0 +1,146 @@
+package lfa
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// alphabetDeclaration opens a pattern-level alphabet declaration, e.g.
+// "(?alphabet=[a-c0-9])ab*", which sets the universe of '.', negated classes
+// and complements for that pattern
+const alphabetDeclaration = "(?alphabet="
+
+// foldCase returns the set extended with every case variant of its runes
+func foldCase(set Alphabet) Alphabet {
+	folded := append(Alphabet{}, set...)
+	for _, c := range set.Runes() {
+		for f := unicode.SimpleFold(c); f != c; f = unicode.SimpleFold(f) {
+			folded = append(folded, Sym(f))
+		}
+	}
+	return folded.Normalize()
+}
+
+// intersectAlphabets returns the runes that are both in a and in b
+func intersectAlphabets(a, b Alphabet) Alphabet {
+	return b.Complement(a).Complement(a)
+}
+
+// parseFlagGroup parses a group starting with "(?" other than "(?:", with
+// the current position right after the '?'. It handles the alphabet
+// declaration, flag settings "(?i)" and "(?-i)" that last until the end of
+// the enclosing group, and scoped flags "(?i:re)". The returned node is nil
+// when the group only changes settings.
+func (r *Regex) parseFlagGroup(start int) (RegexNode, error) {
+	if strings.HasPrefix(r.expression[start:], alphabetDeclaration) {
+		return nil, r.parseAlphabetDeclaration(start)
+	}
+
+	fold, set := r.foldCase, true
+	for {
+		if r.position >= len(r.expression) {
+			return nil, r.errorAt(r.position, "')' or ':' after the flags")
+		}
+		switch r.expression[r.position] {
+		case 'i':
+			fold = set
+		case '-':
+			if !set {
+				return nil, r.errorAt(r.position, "a flag")
+			}
+			set = false
+		case ')':
+			r.position++
+			r.foldCase = fold
+			return nil, nil
+		case ':':
+			r.position++
+			saved := r.foldCase
+			r.foldCase = fold
+			node, err := r.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			if r.position >= len(r.expression) || r.expression[r.position] != ')' {
+				return nil, r.errorAt(r.position, "')'")
+			}
+			r.position++
+			r.foldCase = saved
+			return node, nil
+		default:
+			return nil, r.errorAt(r.position, "a flag (i), ')' or ':'")
+		}
+		r.position++
+	}
+}
+
+// parseAlphabetDeclaration parses "(?alphabet=[...])" at start, which must
+// be the beginning of the pattern
+func (r *Regex) parseAlphabetDeclaration(start int) error {
+	if start != 0 {
+		return r.errorFound(start, "the alphabet to be declared at the start of the pattern", "an alphabet declaration")
+	}
+	r.position = start + len(alphabetDeclaration)
+
+	if r.position >= len(r.expression) || r.expression[r.position] != '[' {
+		return r.errorAt(r.position, "a bracket expression")
+	}
+	classStart := r.position
+	class, err := r.parseClass()
+	if err != nil {
+		return err
+	}
+	if class.Negated {
+		return r.errorFound(classStart, "a bracket expression listing the alphabet", "a negated class")
+	}
+
+	if r.position >= len(r.expression) || r.expression[r.position] != ')' {
+		return r.errorAt(r.position, "')'")
+	}
+	r.position++
+
+	r.alphabet = class.Set.Normalize()
+	r.declared = r.alphabet
+	return nil
+}
+
+// applyFlags adapts a literal or class parsed at offset to the active flags
+// and to the declared alphabet: case folding turns a literal into the class
+// of its case variants, and with a declared alphabet literals must belong to
+// it while classes are restricted to it.
+func (r *Regex) applyFlags(node RegexNode, offset int) (RegexNode, error) {
+	switch n := node.(type) {
+	case LiteralNode:
+		set := Alphabet{Sym(n.Char)}
+		if r.foldCase {
+			set = foldCase(set)
+		}
+		if r.declared != nil {
+			set = intersectAlphabets(set, r.declared)
+			if len(set) == 0 {
+				return nil, r.errorFound(offset, fmt.Sprintf("a character of the declared alphabet %s", r.declared), fmt.Sprintf("%q", n.Char))
+			}
+		}
+		if len(set) == 1 && set[0].Lo == set[0].Hi {
+			return LiteralNode{Char: set[0].Lo}, nil
+		}
+		return ClassNode{Set: set}, nil
+
+	case ClassNode:
+		if r.foldCase {
+			n.Set = foldCase(n.Set)
+		}
+		if r.declared != nil && !n.Negated {
+			n.Set = intersectAlphabets(n.Set, r.declared)
+		}
+		return n, nil
+	}
+	return node, nil
+}
+
+// withDeclaredAlphabet adds the declared alphabet, if any, to the alphabet
+// of an automaton built from the pattern
+func (r *Regex) withDeclaredAlphabet(sigma []RuneRange) []RuneRange {
+	return append(sigma, r.declared...)
+}
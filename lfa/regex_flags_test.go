@@ -0,0 +1,145 @@
+package lfa
+
+import "testing"
+
+func TestRegexCaseFolding(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		accepted []string
+		rejected []string
+	}{
+		{"(?i)ab", []string{"ab", "AB", "aB", "Ab"}, []string{"abc", "ac"}},
+		{"a(?i)b", []string{"ab", "aB"}, []string{"Ab", "AB"}},
+		{"(?i:a)b", []string{"ab", "Ab"}, []string{"aB", "AB"}},
+		{"((?i)a)b", []string{"ab", "Ab"}, []string{"aB"}},
+		{"(?i)a(?-i)b", []string{"ab", "Ab"}, []string{"aB"}},
+		{"(?i)[a-c]x", []string{"bx", "BX", "Cx"}, []string{"dx", "DX"}},
+		{"(?i)[^a]", []string{"b", "B", "1"}, []string{"a", "A"}},
+		{"(?i)1|é", []string{"1", "é", "É"}, []string{"e", "2"}},
+		// A scoped group turning folding off is not folded again after it
+		{"(?i)(?-i:A)", []string{"A"}, []string{"a"}},
+		{"(?i)(?-i:[A-C])x", []string{"Bx", "BX"}, []string{"bx", "bX"}},
+		{"(?i)(?:(?-i)A)b", []string{"AB", "Ab"}, []string{"ab"}},
+		{"(?i:a)*", []string{"", "aA", "AAa"}, []string{"b"}},
+	}
+
+	for _, tt := range tests {
+		nfa, err := CreateNFAFromRegex(tt.pattern)
+		if err != nil {
+			t.Fatalf("'%s': %v", tt.pattern, err)
+		}
+		for _, w := range tt.accepted {
+			if !nfa.Accept(w) {
+				t.Errorf("'%s' should accept '%s'", tt.pattern, w)
+			}
+		}
+		for _, w := range tt.rejected {
+			if nfa.Accept(w) {
+				t.Errorf("'%s' should reject '%s'", tt.pattern, w)
+			}
+		}
+
+		// The derivative and position constructions see the same classes
+		dfa, err := NewRegex(tt.pattern).DerivativeDFA()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, w := range append(tt.accepted, tt.rejected...) {
+			if dfa.Accept(w) != nfa.Accept(w) {
+				t.Errorf("'%s': derivative DFA and NFA disagree on '%s'", tt.pattern, w)
+			}
+		}
+	}
+
+	// Folding expands a literal into one edge per case variant
+	nfa, _ := CreateNFAFromRegex("(?i)k")
+	edges := 0
+	for _, transitions := range nfa.Delta {
+		edges += len(transitions)
+	}
+	// k, K and the Kelvin sign
+	if edges != 3 {
+		t.Errorf("expected 3 edges for (?i)k, got %d", edges)
+	}
+}
+
+func TestRegexDeclaredAlphabet(t *testing.T) {
+	nfa, err := CreateNFAFromRegex("(?alphabet=[abc])a.~(b*)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nfa.Sigma.String(); got != "[a-c]" {
+		t.Errorf("expected Sigma [a-c], got %s", got)
+	}
+	for _, w := range []string{"aaa", "acc", "abba", "abbc"} {
+		if !nfa.Accept(w) {
+			t.Errorf("should accept '%s'", w)
+		}
+	}
+	for _, w := range []string{"aa", "ab", "aab", "abbb", "adc", "aXc"} {
+		if nfa.Accept(w) {
+			t.Errorf("should reject '%s'", w)
+		}
+	}
+
+	// The other constructions use the declared alphabet as well
+	r := NewRegex("(?alphabet=[a-d])a[^b]")
+	node, err := r.ParseAST()
+	if err != nil {
+		t.Fatal(err)
+	}
+	glushkov, err := r.Glushkov(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := glushkov.Sigma.String(); got != "[a-d]" {
+		t.Errorf("expected Glushkov Sigma [a-d], got %s", got)
+	}
+	if dfa := r.CompileDFA(node); dfa.Sigma.String() != "[a-d]" || !dfa.Accept("ad") || dfa.Accept("ae") {
+		t.Errorf("unexpected derivative DFA over %s", dfa.Sigma)
+	}
+
+	// Classes are restricted to the alphabet, and folding keeps to it
+	nfa, err = CreateNFAFromRegex("(?alphabet=[a-cB])(?i)[a-z]b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !nfa.Accept("cB") || !nfa.Accept("Bb") || nfa.Accept("db") {
+		t.Error("classes should be restricted to the declared alphabet")
+	}
+
+	// Without a declaration Sigma only holds the symbols used
+	nfa, _ = CreateNFAFromRegex("ab")
+	if got := nfa.Sigma.String(); got != "[ab]" {
+		t.Errorf("expected Sigma [ab], got %s", got)
+	}
+}
+
+func TestRegexFlagErrors(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		offset   int
+		expected string
+	}{
+		{"(?x)a", 2, "a flag (i), ')' or ':'"},
+		{"(?i", 3, "')' or ':' after the flags"},
+		{"(?i--i)", 4, "a flag"},
+		{"(?i)*", 4, "an expression before the quantifier"},
+		{"~(?i)", 1, "an expression after '~'"},
+		{"a(?alphabet=[ab])", 1, "the alphabet to be declared at the start of the pattern"},
+		{"(?alphabet=ab)", 11, "a bracket expression"},
+		{"(?alphabet=[^a])", 11, "a bracket expression listing the alphabet"},
+		{"(?alphabet=[ab])ac", 17, "a character of the declared alphabet [ab]"},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseRegex(tt.pattern)
+		regexErr, ok := err.(*RegexError)
+		if !ok {
+			t.Fatalf("'%s': expected a *RegexError, got %v", tt.pattern, err)
+		}
+		if regexErr.Offset != tt.offset || regexErr.Expected != tt.expected {
+			t.Errorf("'%s': got offset %d, expected %q", tt.pattern, regexErr.Offset, regexErr.Expected)
+		}
+	}
+}
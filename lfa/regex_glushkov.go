@@ -0,0 +1,297 @@
+package lfa
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// positionInfo holds the nullable/first/last attributes of a subexpression
+// in the position (Glushkov) construction
+type positionInfo struct {
+	nullable bool
+	first    []int
+	last     []int
+}
+
+// positions is the linearized form of an expression: every occurrence of a
+// literal or class is a position numbered from 1, with the runes it matches
+// and the positions that can follow it
+type positions struct {
+	labels []Alphabet // labels[p-1] for position p
+	follow map[int]map[int]bool
+	info   positionInfo
+}
+
+func (ps *positions) addFollow(from []int, to []int) {
+	for _, p := range from {
+		if ps.follow[p] == nil {
+			ps.follow[p] = make(map[int]bool)
+		}
+		for _, q := range to {
+			ps.follow[p][q] = true
+		}
+	}
+}
+
+func unionPositions(a, b []int) []int {
+	return append(append([]int{}, a...), b...)
+}
+
+// linearize numbers the positions of node and computes followpos
+func (r *Regex) linearize(node RegexNode) (*positions, error) {
+	if err := CheckAnchors(node); err != nil {
+		return nil, err
+	}
+	ps := &positions{follow: make(map[int]map[int]bool)}
+
+	var walk func(n RegexNode) (positionInfo, error)
+	walk = func(n RegexNode) (positionInfo, error) {
+		switch n := n.(type) {
+		case EmptyNode:
+			return positionInfo{}, nil
+
+		case EpsilonNode, AnchorNode:
+			return positionInfo{nullable: true}, nil
+
+		case LiteralNode, ClassNode:
+			label := Alphabet{}
+			if lit, ok := n.(LiteralNode); ok {
+				label = Alphabet{Sym(lit.Char)}
+			} else {
+				label = r.resolveClass(n.(ClassNode))
+			}
+			ps.labels = append(ps.labels, label)
+			p := len(ps.labels)
+			return positionInfo{first: []int{p}, last: []int{p}}, nil
+
+		case ConcatNode:
+			info := positionInfo{nullable: true}
+			for _, part := range n.Parts {
+				next, err := walk(part)
+				if err != nil {
+					return positionInfo{}, err
+				}
+				ps.addFollow(info.last, next.first)
+
+				if info.nullable {
+					info.first = unionPositions(info.first, next.first)
+				}
+				if next.nullable {
+					info.last = unionPositions(info.last, next.last)
+				} else {
+					info.last = next.last
+				}
+				info.nullable = info.nullable && next.nullable
+			}
+			return info, nil
+
+		case AltNode:
+			info := positionInfo{}
+			for _, option := range n.Options {
+				next, err := walk(option)
+				if err != nil {
+					return positionInfo{}, err
+				}
+				info.nullable = info.nullable || next.nullable
+				info.first = unionPositions(info.first, next.first)
+				info.last = unionPositions(info.last, next.last)
+			}
+			return info, nil
+
+		case StarNode, PlusNode:
+			var sub RegexNode
+			if star, ok := n.(StarNode); ok {
+				sub = star.Sub
+			} else {
+				sub = n.(PlusNode).Sub
+			}
+			info, err := walk(sub)
+			if err != nil {
+				return positionInfo{}, err
+			}
+			ps.addFollow(info.last, info.first)
+			if n.Kind() == RegexStar {
+				info.nullable = true
+			}
+			return info, nil
+
+		case GroupNode:
+			return walk(n.Sub)
+
+		case OptionalNode:
+			info, err := walk(n.Sub)
+			info.nullable = true
+			return info, err
+
+		case RepeatNode:
+			// Positions are occurrences, so r{m,n} is unrolled into m copies
+			// of r followed by n-m optional copies (or r* when unbounded)
+			parts := make([]RegexNode, 0)
+			for i := 0; i < n.Min; i++ {
+				parts = append(parts, n.Sub)
+			}
+			if n.Max < 0 {
+				parts = append(parts, StarNode{Sub: n.Sub})
+			}
+			for i := n.Min; i < n.Max; i++ {
+				parts = append(parts, OptionalNode{Sub: n.Sub})
+			}
+			if len(parts) == 0 {
+				return positionInfo{nullable: true}, nil
+			}
+			return walk(ConcatNode{Parts: parts})
+		}
+
+		return positionInfo{}, fmt.Errorf("the position construction does not support %s", n)
+	}
+
+	info, err := walk(node)
+	if err != nil {
+		return nil, err
+	}
+	ps.info = info
+	return ps, nil
+}
+
+// Glushkov builds the position automaton of an expression: one state per
+// occurrence of a literal or class plus an initial state, so n positions give
+// exactly n+1 states and no epsilon transitions. Every edge into position p
+// is labelled with the runes of p. Intersection and complement have no
+// position construction and are reported as errors, like misplaced anchors
+// (see CheckAnchors).
+func (r *Regex) Glushkov(node RegexNode) (*NFA, error) {
+	ps, err := r.linearize(node)
+	if err != nil {
+		return nil, err
+	}
+
+	name := make([]State, len(ps.labels)+1)
+	for p := range name {
+		name[p] = fmt.Sprintf("%s%d", r.statePrefix, r.stateCounter)
+		r.stateCounter++
+	}
+
+	delta := make(DeltaNfa)
+	edge := func(from State, to int) {
+		for _, symbol := range ps.labels[to-1] {
+			delta.Add(from, symbol, NewSetState(name[to]))
+		}
+	}
+
+	for _, p := range ps.info.first {
+		edge(name[0], p)
+	}
+	for p := 1; p <= len(ps.labels); p++ {
+		for q := range ps.follow[p] {
+			edge(name[p], q)
+		}
+	}
+
+	final := make([]State, 0)
+	if ps.info.nullable {
+		final = append(final, name[0])
+	}
+	for _, p := range ps.info.last {
+		if !contains(final, name[p]) {
+			final = append(final, name[p])
+		}
+	}
+
+	sigma := make(Alphabet, 0)
+	for _, label := range ps.labels {
+		sigma = append(sigma, label...)
+	}
+
+	sigma = r.withDeclaredAlphabet(sigma)
+	return NewNFA(name, sigma.Normalize(), delta, []State{name[0]}, final), nil
+}
+
+// positionSetName renders a set of positions as "{1,3,#}", where # is the
+// end marker
+func positionSetName(set map[int]bool, end int) State {
+	ids := make([]int, 0, len(set))
+	for p := range set {
+		ids = append(ids, p)
+	}
+	sort.Ints(ids)
+
+	names := make([]string, 0, len(ids))
+	for _, p := range ids {
+		if p == end {
+			names = append(names, "#")
+		} else {
+			names = append(names, strconv.Itoa(p))
+		}
+	}
+	return "{" + strings.Join(names, ",") + "}"
+}
+
+// PositionDFA builds a DFA directly from the expression with the followpos
+// algorithm of Aho, Sethi and Ullman. The expression is augmented with an end
+// marker #, DFA states are sets of positions named like "{1,2,#}", and a
+// state is final when it contains #. Transitions are computed on the ranges
+// obtained by splitting all position labels, so the result is partial like
+// the one of ToDFA.
+func (r *Regex) PositionDFA(node RegexNode) (*DFA, error) {
+	ps, err := r.linearize(node)
+	if err != nil {
+		return nil, err
+	}
+
+	// The end marker follows every last position and matches nothing
+	end := len(ps.labels) + 1
+	ps.addFollow(ps.info.last, []int{end})
+
+	labels := make([]RuneRange, 0)
+	for _, label := range ps.labels {
+		labels = append(labels, label...)
+	}
+	sigma := Alphabet(splitRanges(r.withDeclaredAlphabet(labels)))
+
+	start := make(map[int]bool)
+	for _, p := range ps.info.first {
+		start[p] = true
+	}
+	if ps.info.nullable {
+		start[end] = true
+	}
+
+	states := []State{positionSetName(start, end)}
+	final := make([]State, 0)
+	delta := make(DeltaDFA)
+	queue := []map[int]bool{start}
+
+	for len(queue) > 0 {
+		set := queue[0]
+		queue = queue[1:]
+		from := positionSetName(set, end)
+		if set[end] {
+			final = append(final, from)
+		}
+
+		for _, symbol := range sigma {
+			next := make(map[int]bool)
+			for p := range set {
+				if p != end && ps.labels[p-1].Contains(symbol.Lo) {
+					for q := range ps.follow[p] {
+						next[q] = true
+					}
+				}
+			}
+			if len(next) == 0 {
+				continue
+			}
+
+			to := positionSetName(next, end)
+			if !contains(states, to) {
+				states = append(states, to)
+				queue = append(queue, next)
+			}
+			delta.Add(from, symbol, to)
+		}
+	}
+
+	return NewDFA(states, sigma, delta, states[0], final), nil
+}
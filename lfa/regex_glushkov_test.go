@@ -0,0 +1,85 @@
+package lfa
+
+import "testing"
+
+func TestRegexPositionConstructions(t *testing.T) {
+	patterns := []string{
+		"(a|b)*abb",
+		"a+b?(ab)*",
+		"(a|ab)(b|)",
+		"[ab]{2,3}b*",
+		"a{2,}b",
+		".a.",
+		"()",
+		"[]a|b",
+		"((a*)*|b)+",
+	}
+
+	for _, pattern := range patterns {
+		thompson, err := CreateNFAFromRegex(pattern)
+		if err != nil {
+			t.Fatalf("Failed to create NFA from regex '%s': %v", pattern, err)
+		}
+
+		r := NewRegex(pattern)
+		r.SetAlphabet(NewAlphabet('a', 'b'))
+		node, err := r.ParseAST()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		glushkov, err := r.Glushkov(node)
+		if err != nil {
+			t.Fatalf("Glushkov('%s'): %v", pattern, err)
+		}
+		for _, transitions := range glushkov.Delta {
+			if _, ok := transitions[Epsilon]; ok {
+				t.Fatalf("Glushkov automaton of '%s' has an epsilon transition", pattern)
+			}
+		}
+
+		dfa, err := r.PositionDFA(node)
+		if err != nil {
+			t.Fatalf("PositionDFA('%s'): %v", pattern, err)
+		}
+
+		for _, w := range allWords("ab", 7) {
+			want := thompson.Accept(w)
+			if got := glushkov.Accept(w); got != want {
+				t.Fatalf("Glushkov automaton of '%s' on %q = %v, Thompson NFA says %v", pattern, w, got, want)
+			}
+			if got := dfa.Accept(w); got != want {
+				t.Fatalf("followpos DFA of '%s' on %q = %v, Thompson NFA says %v", pattern, w, got, want)
+			}
+		}
+	}
+}
+
+func TestGlushkovStateCount(t *testing.T) {
+	r := NewRegex("(a|b)*abb")
+	node, _ := r.ParseAST()
+
+	nfa, err := r.Glushkov(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Five symbol positions a, b, a, b, b
+	if len(nfa.Q) != 6 {
+		t.Errorf("expected 6 states, got %d", len(nfa.Q))
+	}
+
+	dfa, err := r.PositionDFA(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dfa.Q) != 4 || dfa.Q0 != "{1,2,3}" {
+		t.Errorf("expected the 4-state DFA starting in {1,2,3}, got %v from %s", dfa.Q, dfa.Q0)
+	}
+	if !contains(dfa.F, "{1,2,3,#}") {
+		t.Errorf("expected {1,2,3,#} to be final, got %v", dfa.F)
+	}
+
+	if _, err := r.Glushkov(NotNode{Sub: node}); err == nil {
+		t.Error("expected an error for a complement")
+	}
+}
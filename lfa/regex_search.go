@@ -0,0 +1,195 @@
+package lfa
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// findAt searches s for the leftmost-longest match starting at or after
+// from and returns its slots, or nil. Instead of running the automaton once
+// per start offset, a fresh thread is started at every offset as if the
+// pattern were prefixed with .*: threads with an earlier start win when they
+// meet, and no new thread is started once a match has been found.
+func (t *TaggedNFA) findAt(s string, from int) []int {
+	var best []int
+	current := newThreadList()
+
+	for pos := from; ; {
+		if best == nil {
+			t.addThread(current, t.start, t.newSlots(), pos, len(s))
+		}
+
+		for _, state := range current.order {
+			if t.states[state].kind == tagMatch {
+				if slots := current.slots[state]; best == nil || posixBetter(slots, best) {
+					best = slots
+				}
+			}
+		}
+
+		if pos >= len(s) {
+			break
+		}
+
+		c, size := utf8.DecodeRuneInString(s[pos:])
+		next := newThreadList()
+		for _, state := range current.order {
+			st := t.states[state]
+			slots := current.slots[state]
+			// A thread starting after the best match can no longer beat it
+			if best != nil && slots[0] > best[0] {
+				continue
+			}
+			if st.kind == tagRune && st.set.Contains(c) {
+				t.addThread(next, st.out[0], slots, pos+size, len(s))
+			}
+		}
+		current = next
+		pos += size
+
+		if best != nil && len(current.order) == 0 {
+			break
+		}
+	}
+
+	return best
+}
+
+// FindSubmatchIndex returns the offsets of the leftmost-longest match of the
+// pattern in s and of its groups, laid out as for MatchSubmatch, or nil
+func (t *TaggedNFA) FindSubmatchIndex(s string) []int {
+	return t.findAt(s, 0)
+}
+
+// FindIndex returns the byte offsets [start, end) of the leftmost-longest
+// match of the pattern in s, or nil if there is none
+func (t *TaggedNFA) FindIndex(s string) []int {
+	m := t.findAt(s, 0)
+	if m == nil {
+		return nil
+	}
+	return m[:2]
+}
+
+// FindString returns the text of the leftmost-longest match in s, or "" if
+// there is none; use FindIndex to tell an empty match from no match
+func (t *TaggedNFA) FindString(s string) string {
+	m := t.findAt(s, 0)
+	if m == nil {
+		return ""
+	}
+	return s[m[0]:m[1]]
+}
+
+// findAll returns the slots of successive non-overlapping matches, at most n
+// of them when n >= 0. As in package regexp, an empty match right after the
+// previous match is ignored.
+func (t *TaggedNFA) findAll(s string, n int) [][]int {
+	matches := make([][]int, 0)
+	prevEnd := -1
+
+	for pos := 0; pos <= len(s) && (n < 0 || len(matches) < n); {
+		m := t.findAt(s, pos)
+		if m == nil {
+			break
+		}
+
+		if m[0] != m[1] || m[0] != prevEnd {
+			matches = append(matches, m)
+		}
+		prevEnd = m[1]
+
+		if m[1] > pos {
+			pos = m[1]
+		} else if pos < len(s) {
+			_, size := utf8.DecodeRuneInString(s[pos:])
+			pos += size
+		} else {
+			break
+		}
+	}
+
+	return matches
+}
+
+// FindAllIndex returns the offsets of all successive non-overlapping
+// leftmost-longest matches, at most n of them unless n is negative
+func (t *TaggedNFA) FindAllIndex(s string, n int) [][]int {
+	matches := t.findAll(s, n)
+	for i, m := range matches {
+		matches[i] = m[:2]
+	}
+	return matches
+}
+
+// FindAllString returns the texts of all successive non-overlapping matches,
+// at most n of them unless n is negative
+func (t *TaggedNFA) FindAllString(s string, n int) []string {
+	texts := make([]string, 0)
+	for _, m := range t.findAll(s, n) {
+		texts = append(texts, s[m[0]:m[1]])
+	}
+	return texts
+}
+
+// ReplaceAll returns a copy of s where every match is replaced by repl. In
+// repl, $n or ${n} stands for the text of group n ($0 for the whole match)
+// and $$ for a literal $. A reference that is not a group number, like
+// ${-1}, is copied as is.
+func (t *TaggedNFA) ReplaceAll(s, repl string) string {
+	var sb strings.Builder
+	last := 0
+
+	for _, m := range t.findAll(s, -1) {
+		sb.WriteString(s[last:m[0]])
+		t.expand(&sb, repl, s, m)
+		last = m[1]
+	}
+	sb.WriteString(s[last:])
+
+	return sb.String()
+}
+
+// expand writes repl with its group references replaced from the match m
+func (t *TaggedNFA) expand(sb *strings.Builder, repl, s string, m []int) {
+	for i := 0; i < len(repl); i++ {
+		if repl[i] != '$' || i+1 == len(repl) {
+			sb.WriteByte(repl[i])
+			continue
+		}
+
+		if repl[i+1] == '$' {
+			sb.WriteByte('$')
+			i++
+			continue
+		}
+
+		// Read the group number, either ${n} or the digits after $
+		start, end, next := i+1, i+1, i+1
+		if repl[start] == '{' {
+			closing := strings.IndexByte(repl[start:], '}')
+			if closing < 0 {
+				sb.WriteByte('$')
+				continue
+			}
+			start, end = start+1, start+closing
+			next = end + 1
+		} else {
+			for end < len(repl) && repl[end] >= '0' && repl[end] <= '9' {
+				end++
+			}
+			next = end
+		}
+
+		group, err := strconv.Atoi(repl[start:end])
+		if err != nil || group < 0 {
+			sb.WriteByte('$')
+			continue
+		}
+		if group <= t.groups && m[2*group] >= 0 {
+			sb.WriteString(s[m[2*group]:m[2*group+1]])
+		}
+		i = next - 1
+	}
+}
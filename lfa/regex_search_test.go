@@ -0,0 +1,121 @@
+package lfa
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestRegexFindAgreesWithLongestRegexp(t *testing.T) {
+	patterns := []string{"a+", "ab|a", "b*", "(a|b)*abb", "^a*", "b$", "a(ba)*", "^$", "ab?|ba"}
+	texts := append(allWords("ab", 5), "xxabbaby", "babba", "")
+
+	for _, pattern := range patterns {
+		program, err := CompilePattern(pattern)
+		if err != nil {
+			t.Fatalf("Failed to compile '%s': %v", pattern, err)
+		}
+		re := regexp.MustCompile(pattern)
+		re.Longest()
+
+		for _, text := range texts {
+			if got, want := program.FindIndex(text), re.FindStringIndex(text); !reflect.DeepEqual(got, want) {
+				t.Fatalf("FindIndex('%s', %q) = %v, expected %v", pattern, text, got, want)
+			}
+
+			got, want := program.FindAllIndex(text, -1), re.FindAllStringIndex(text, -1)
+			if len(got) != 0 || len(want) != 0 {
+				if !reflect.DeepEqual(got, want) {
+					t.Fatalf("FindAllIndex('%s', %q) = %v, expected %v", pattern, text, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestRegexFindAndReplace(t *testing.T) {
+	program, err := CompilePattern(`(\w+)@(\w+)\.com`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text := "mail ana@utm.com or ion@mail.com, not bob@site.org"
+	if got := program.FindString(text); got != "ana@utm.com" {
+		t.Errorf("FindString = %q", got)
+	}
+	if got := program.FindAllString(text, 1); !reflect.DeepEqual(got, []string{"ana@utm.com"}) {
+		t.Errorf("FindAllString with n=1 = %q", got)
+	}
+	if got := program.FindSubmatchIndex(text); !reflect.DeepEqual(got, []int{5, 16, 5, 8, 9, 12}) {
+		t.Errorf("FindSubmatchIndex = %v", got)
+	}
+
+	want := "mail utm:ana or mail:ion, not bob@site.org"
+	if got := program.ReplaceAll(text, "$2:${1}"); got != want {
+		t.Errorf("ReplaceAll = %q, expected %q", got, want)
+	}
+
+	dollar, _ := CompilePattern("a")
+	if got := dollar.ReplaceAll("banana", "$$$0}"); got != "b$a}n$a}n$a}" {
+		t.Errorf("ReplaceAll with $$ = %q", got)
+	}
+	if got := dollar.ReplaceAll("ab a", "[${-1}]"); got != "[${-1}]b [${-1}]" {
+		t.Errorf("ReplaceAll with an invalid group = %q", got)
+	}
+
+	if program.FindIndex("nothing here") != nil {
+		t.Error("expected no match")
+	}
+}
+
+func TestRegexAnchors(t *testing.T) {
+	program, _ := CompilePattern("^ab|b$")
+	if got := program.FindAllString("abab", -1); !reflect.DeepEqual(got, []string{"ab", "b"}) {
+		t.Errorf("FindAllString = %q", got)
+	}
+
+	// Whole-input matching anchors both ends already
+	nfa, err := CreateNFAFromRegex("^a*$")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !nfa.Accept("aaa") || nfa.Accept("aab") {
+		t.Error("anchors changed whole-input matching")
+	}
+
+	// Anchors inside the pattern are rejected rather than read as ε, so
+	// every whole-input API agrees with MatchSubmatch
+	misplaced := []struct{ pattern, word string }{
+		{"a^b", "ab"}, {"a$b", "ab"}, {"(a$)*b", "ab"}, {"(^a)*", "aa"}, {"a|b^", "b"},
+	}
+	for _, m := range misplaced {
+		pattern := m.pattern
+		program, err := CompilePattern(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if program.MatchSubmatch(m.word) != nil {
+			t.Errorf("'%s' should not match %q as a whole", pattern, m.word)
+		}
+
+		r := NewRegex(pattern)
+		if _, err := r.Parse(); err == nil {
+			t.Errorf("'%s': Parse accepted a misplaced anchor", pattern)
+		}
+		if _, err := r.Match(m.word); err == nil {
+			t.Errorf("'%s': Match accepted a misplaced anchor", pattern)
+		}
+		node, _ := r.ParseAST()
+		if _, err := r.Glushkov(node); err == nil {
+			t.Errorf("'%s': Glushkov accepted a misplaced anchor", pattern)
+		}
+		if _, err := r.CompileCounting(node); err == nil {
+			t.Errorf("'%s': CompileCounting accepted a misplaced anchor", pattern)
+		}
+	}
+	for _, pattern := range []string{"^a|^b$", "(^a$)", "$^", "^(a|b)*c$$"} {
+		if node, _ := ParseRegex(pattern); CheckAnchors(node) != nil {
+			t.Errorf("'%s': anchors at the ends should be accepted", pattern)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package lfa
+
+import (
+	"fmt"
+	"sort"
+)
+
+// RegexSet matches an input against many patterns at once. The Thompson
+// NFAs of the patterns are joined under a common start state, and their
+// final states are tagged with the index of the pattern they belong to, so a
+// single pass over the input tells which patterns accept it.
+type RegexSet struct {
+	patterns []string
+	nfa      *NFA
+	tags     map[State]int
+}
+
+// NewRegexSet compiles the patterns into a single tagged automaton. States
+// are numbered from one counter, so the patterns never share states.
+func NewRegexSet(patterns ...string) (*RegexSet, error) {
+	counter := 0
+	start := fmt.Sprintf("q%d", counter)
+	counter++
+
+	states := []State{start}
+	sigma := make(Alphabet, 0)
+	delta := make(DeltaNfa)
+	final := make([]State, 0)
+	tags := make(map[State]int)
+
+	for i, pattern := range patterns {
+		r := NewRegex(pattern)
+		r.stateCounter = counter
+		nfa, err := r.Parse()
+		if err != nil {
+			return nil, fmt.Errorf("pattern %d: %w", i, err)
+		}
+		counter = r.stateCounter
+
+		states = append(states, nfa.Q...)
+		sigma = append(sigma, nfa.Sigma...)
+		for from, transitions := range nfa.Delta {
+			for symbol, to := range transitions {
+				delta.Add(from, symbol, to)
+			}
+		}
+		delta.Add(start, Epsilon, NewSetState(nfa.Q0...))
+		for _, f := range nfa.F {
+			final = append(final, f)
+			tags[f] = i
+		}
+	}
+
+	return &RegexSet{
+		patterns: append([]string{}, patterns...),
+		nfa:      NewNFA(states, sigma, delta, []State{start}, final),
+		tags:     tags,
+	}, nil
+}
+
+// Len returns the number of patterns in the set
+func (s *RegexSet) Len() int {
+	return len(s.patterns)
+}
+
+// Patterns returns the patterns of the set, in the order they were given
+func (s *RegexSet) Patterns() []string {
+	return append([]string{}, s.patterns...)
+}
+
+// NFA returns the combined automaton
+func (s *RegexSet) NFA() *NFA {
+	return s.nfa
+}
+
+// Pattern returns the index of the pattern a final state belongs to
+func (s *RegexSet) Pattern(state State) (int, bool) {
+	i, ok := s.tags[state]
+	return i, ok
+}
+
+// Matches returns the indices of the patterns accepting the whole input, in
+// increasing order. The input is read once for all patterns.
+func (s *RegexSet) Matches(input string) []int {
+	current := s.nfa.EpsilonClosureSet(NewSetState(s.nfa.Q0...))
+
+	for _, c := range input {
+		next := make(setState)
+		for state := range current {
+			if reachable := s.nfa.Delta.LookupRune(state, c); reachable != nil {
+				next.Union(reachable)
+			}
+		}
+		current = s.nfa.EpsilonClosureSet(next)
+
+		if len(current) == 0 {
+			return []int{}
+		}
+	}
+
+	seen := make(map[int]bool)
+	matches := make([]int, 0)
+	for state := range current {
+		if i, ok := s.tags[state]; ok && !seen[i] {
+			seen[i] = true
+			matches = append(matches, i)
+		}
+	}
+	sort.Ints(matches)
+	return matches
+}
+
+// MatchString reports whether any pattern of the set accepts the input
+func (s *RegexSet) MatchString(input string) bool {
+	return len(s.Matches(input)) > 0
+}
@@ -0,0 +1,76 @@
+package lfa
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRegexSetMatches(t *testing.T) {
+	patterns := []string{
+		"(a|b)*abb",
+		"a+b?",
+		"[ab]{2}",
+		"b*",
+		"~(a*)&[ab]*",
+		"(ab)*",
+	}
+
+	set, err := NewRegexSet(patterns...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set.Len() != len(patterns) {
+		t.Fatalf("expected %d patterns, got %d", len(patterns), set.Len())
+	}
+
+	nfas := make([]*NFA, len(patterns))
+	for i, pattern := range patterns {
+		r := NewRegex(pattern)
+		nfas[i], err = r.Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, w := range allWords("abc", 5) {
+		want := make([]int, 0)
+		for i, nfa := range nfas {
+			if nfa.Accept(w) {
+				want = append(want, i)
+			}
+		}
+		if got := set.Matches(w); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Matches(%q) = %v, expected %v", w, got, want)
+		}
+		if set.MatchString(w) != (len(want) > 0) {
+			t.Fatalf("MatchString(%q) should be %v", w, len(want) > 0)
+		}
+	}
+
+	// Every final state is tagged with its pattern
+	for _, f := range set.NFA().F {
+		if _, ok := set.Pattern(f); !ok {
+			t.Errorf("final state %s has no pattern", f)
+		}
+	}
+	if _, ok := set.Pattern(set.NFA().Q0[0]); ok {
+		t.Error("the start state should not be tagged")
+	}
+}
+
+func TestRegexSetErrors(t *testing.T) {
+	_, err := NewRegexSet("ab", "a(b")
+	var regexErr *RegexError
+	if !errors.As(err, &regexErr) || regexErr.Pattern != "a(b" {
+		t.Fatalf("expected the error of the second pattern, got %v", err)
+	}
+
+	set, err := NewRegexSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := set.Matches(""); len(got) != 0 {
+		t.Errorf("an empty set should match nothing, got %v", got)
+	}
+}
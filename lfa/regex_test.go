@@ -1,7 +1,9 @@
 package lfa
 
 import (
+	"reflect"
 	"regexp"
+	"strings"
 	"testing"
 )
 
@@ -73,3 +75,341 @@ func TestRegexGenWords(t *testing.T) {
 		})
 	}
 }
+
+func TestRegexUnicodeLiterals(t *testing.T) {
+	nfa, err := CreateNFAFromRegex("ñ(ä|ö)*.")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, w := range []string{"ña", "ñäöäZ", "ñö7"} {
+		if !nfa.Accept(w) {
+			t.Fatal("word: ", w, " Rejected")
+		}
+	}
+	for _, w := range []string{"n", "ñä", "ñäö"} {
+		if nfa.Accept(w) {
+			t.Fatal("word: ", w, " Accepted")
+		}
+	}
+}
+
+func TestRegexClassesAndEscapes(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		accept  []string
+		reject  []string
+	}{
+		{`[a-z0-9]+`, []string{"abc", "a1", "9"}, []string{"", "A", "a-b"}},
+		{`[^ab]c`, []string{"cc", "Zc", "0c"}, []string{"ac", "bc", "c"}},
+		{`a\.b`, []string{"a.b"}, []string{"axb", "ab"}},
+		{`\*\(\)\|\\`, []string{`*()|\`}, []string{"*()|"}},
+		{`\d{3}`, []string{"123", "000"}, []string{"12a", "1234"}},
+		{`\w+\s\w+`, []string{"hello world", "a_1\tb"}, []string{"hello  world", "ab"}},
+		{`[-a]+`, []string{"-a-", "a"}, []string{"b"}},
+		{`[\]\-]`, []string{"]", "-"}, []string{"a"}},
+	}
+
+	for _, tc := range testCases {
+		nfa, err := CreateNFAFromRegex(tc.pattern)
+		if err != nil {
+			t.Fatalf("Failed to create NFA from regex '%s': %v", tc.pattern, err)
+		}
+
+		re := regexp.MustCompile("^(?:" + tc.pattern + ")$")
+		for _, w := range tc.accept {
+			if !nfa.Accept(w) || !re.MatchString(w) {
+				t.Errorf("'%s' should accept %q", tc.pattern, w)
+			}
+		}
+		for _, w := range tc.reject {
+			if nfa.Accept(w) {
+				t.Errorf("'%s' should reject %q", tc.pattern, w)
+			}
+		}
+	}
+}
+
+func TestRegexNegationUsesAlphabet(t *testing.T) {
+	regex := NewRegex(`\D\W[^a-z]`)
+	regex.SetAlphabet(Alphabet{Range(' ', '~')})
+	nfa, err := regex.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, w := range []string{"a 1", "Z-Q"} {
+		if !nfa.Accept(w) {
+			t.Fatal("word: ", w, " Rejected")
+		}
+	}
+	// é is outside the printable ASCII alphabet, so no negated class has it
+	for _, w := range []string{"1 1", "ab1", "a a", "é 1"} {
+		if nfa.Accept(w) {
+			t.Fatal("word: ", w, " Accepted")
+		}
+	}
+}
+
+func TestRegexClassIsCompact(t *testing.T) {
+	nfa, err := CreateNFAFromRegex("[a-zA-Z0-9_]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nfa.Q) != 2 {
+		t.Fatalf("a class should compile to 2 states, got %d", len(nfa.Q))
+	}
+	edges := 0
+	for _, transitions := range nfa.Delta {
+		edges += len(transitions)
+	}
+	if edges != 4 {
+		t.Fatalf("[a-zA-Z0-9_] should use one edge per range, got %d", edges)
+	}
+
+	for _, pattern := range []string{`[a-`, `[z-a]`, `a\`, `\q`, `[a-\d]`} {
+		if _, err := CreateNFAFromRegex(pattern); err == nil {
+			t.Errorf("expected an error for '%s'", pattern)
+		}
+	}
+}
+
+// allWords enumerates every word over alphabet of length at most maxLen
+func allWords(alphabet string, maxLen int) []string {
+	words := []string{""}
+	frontier := []string{""}
+	for length := 0; length < maxLen; length++ {
+		next := make([]string, 0)
+		for _, w := range frontier {
+			for _, c := range alphabet {
+				next = append(next, w+string(c))
+			}
+		}
+		words = append(words, next...)
+		frontier = next
+	}
+	return words
+}
+
+func TestRegexBoundedRepetition(t *testing.T) {
+	patterns := []string{
+		"(3|4){2,5}",
+		"a{2,}b",
+		"a{,2}",
+		"(ab){0,1}a{1}",
+		"a{2}b{2}",
+		"(a{1,2}b){2}",
+		"a{2,3}?b*?",
+		"a³",
+		"(ab)¹⁰|b²",
+	}
+
+	for _, pattern := range patterns {
+		nfa, err := CreateNFAFromRegex(pattern)
+		if err != nil {
+			t.Fatalf("Failed to create NFA from regex '%s': %v", pattern, err)
+		}
+
+		goPattern := strings.NewReplacer("³", "{3}", "¹⁰", "{10}", "²", "{2}", "{,", "{0,").Replace(pattern)
+		re := regexp.MustCompile("^(?:" + goPattern + ")$")
+
+		for _, w := range allWords("ab34", 7) {
+			if nfa.Accept(w) != re.MatchString(w) {
+				t.Fatalf("'%s' disagrees with regexp on %q: got %v", pattern, w, nfa.Accept(w))
+			}
+		}
+	}
+
+	for _, pattern := range []string{"a{3,2}", "a{,}", "a{2", "a{x}"} {
+		if _, err := CreateNFAFromRegex(pattern); err == nil {
+			t.Errorf("expected an error for '%s'", pattern)
+		}
+	}
+}
+
+func TestRegexIntersectionComplement(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    func(w string) bool
+	}{
+		{"~(.*aa.*)&(a|b)*", func(w string) bool { return !strings.Contains(w, "aa") }},
+		{"(a|b)*a(a|b)*&(a|b)*b(a|b)*", func(w string) bool {
+			return strings.Contains(w, "a") && strings.Contains(w, "b")
+		}},
+		{"~a", func(w string) bool { return w != "a" }},
+		{"~()", func(w string) bool { return w != "" }},
+		{"a|b&~b", func(w string) bool { return w == "a" }},
+		{"~a*b", func(w string) bool { return strings.HasSuffix(w, "b") && strings.Contains(w[:len(w)-1], "b") }},
+		{"~~(ab)", func(w string) bool { return w == "ab" }},
+	}
+
+	for _, tt := range tests {
+		regex := NewRegex(tt.pattern)
+		regex.SetAlphabet(NewAlphabet('a', 'b'))
+		nfa, err := regex.Parse()
+		if err != nil {
+			t.Fatalf("Failed to create NFA from regex '%s': %v", tt.pattern, err)
+		}
+		dfa, err := regex.DerivativeDFA()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, w := range allWords("ab", 6) {
+			want := tt.want(w)
+			if got := nfa.Accept(w); got != want {
+				t.Fatalf("'%s' on %q: got %v, expected %v", tt.pattern, w, got, want)
+			}
+			if got := dfa.Accept(w); got != want {
+				t.Fatalf("derivative DFA of '%s' on %q: got %v, expected %v", tt.pattern, w, got, want)
+			}
+		}
+
+		// The complement is relative to the alphabet
+		if nfa.Accept("c") {
+			t.Errorf("'%s' accepted a word outside the alphabet", tt.pattern)
+		}
+	}
+}
+
+func TestRegexErrors(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		offset   int
+		expected string
+		found    string
+	}{
+		{"a)b", 1, "end of pattern", "')'"},
+		{"(a|b", 4, "')'", "end of pattern"},
+		{"*a", 0, "an expression before the quantifier", "'*'"},
+		{"a|+", 2, "an expression before the quantifier", "'+'"},
+		{"a**", 2, "an expression before the quantifier", "'*'"},
+		{"a*?+", 3, "an expression before the quantifier", "'+'"},
+		{"a{2}³", 4, "an expression before the quantifier", "'³'"},
+		{"()*", 2, "a non-empty group before the quantifier", "'*'"},
+		{"x(?:){2}", 5, "a non-empty group before the quantifier", "'{'"},
+		{"[a-z", 4, "']'", "end of pattern"},
+		{"[z-a]", 1, "a range whose end is not below its start", "z-a"},
+		{"a{3,2}", 1, "a minimum not greater than the maximum", "{3,2}"},
+		{"ab\\q", 3, "a known escape sequence", "\\q"},
+		{"é)", 2, "end of pattern", "')'"},
+		{"~|a", 1, "an expression after '~'", "'|'"},
+		{"(~)", 2, "an expression after '~'", "')'"},
+		{"a&~", 3, "an expression after '~'", "end of pattern"},
+		{"~&a", 1, "an expression after '~'", "'&'"},
+	}
+
+	for _, tt := range tests {
+		_, err := CreateNFAFromRegex(tt.pattern)
+		regexErr, ok := err.(*RegexError)
+		if !ok {
+			t.Fatalf("'%s': expected a *RegexError, got %v", tt.pattern, err)
+		}
+		if regexErr.Offset != tt.offset || regexErr.Expected != tt.expected || regexErr.Found != tt.found {
+			t.Errorf("'%s': got offset %d, expected %q, found %q", tt.pattern, regexErr.Offset, regexErr.Expected, regexErr.Found)
+		}
+	}
+
+	// Empty groups and alternatives stay valid where they match ε
+	for _, pattern := range []string{"()", "a()b", "(|a)", "a|", "(()){3}", "a*?"} {
+		if _, err := CreateNFAFromRegex(pattern); err != nil {
+			t.Errorf("'%s' should parse: %v", pattern, err)
+		}
+	}
+}
+
+func TestRegexErrorSnippet(t *testing.T) {
+	_, err := ParseRegex("éa(b|c")
+	want := "éa(b|c\n      ^ expected ')', found end of pattern"
+	if got := err.(*RegexError).Snippet(); got != want {
+		t.Errorf("got\n%s\nexpected\n%s", got, want)
+	}
+}
+
+func TestRegexSteps(t *testing.T) {
+	r := NewRegex("(a|b)*c")
+	node, err := r.ParseAST()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nfa := r.Compile(node)
+
+	want := []struct {
+		helper  string
+		pattern string
+		states  int
+		epsilon []EpsilonEdge
+	}{
+		{"CreateBasicNFA", "a", 2, []EpsilonEdge{}},
+		{"CreateBasicNFA", "b", 2, []EpsilonEdge{}},
+		{"UnionNFAs", "a|b", 2, []EpsilonEdge{{"q4", "q0"}, {"q4", "q2"}, {"q1", "q5"}, {"q3", "q5"}}},
+		{"StarNFA", "(a|b)*", 2, []EpsilonEdge{{"q6", "q7"}, {"q6", "q4"}, {"q5", "q7"}, {"q5", "q4"}}},
+		{"CreateBasicNFA", "c", 2, []EpsilonEdge{}},
+		{"ConcatenateNFAs", "(a|b)*c", 0, []EpsilonEdge{{"q7", "q8"}}},
+	}
+
+	steps := r.Steps()
+	if len(steps) != len(want) {
+		t.Fatalf("got %d steps, expected %d: %v", len(steps), len(want), steps)
+	}
+	total := 0
+	for i, w := range want {
+		s := steps[i]
+		if s.Helper != w.helper || s.Pattern != w.pattern || len(s.NewStates) != w.states || !reflect.DeepEqual(s.EpsilonEdges, w.epsilon) {
+			t.Errorf("step %d: got %s", i+1, s)
+		}
+		total += len(s.NewStates)
+	}
+	if total != len(nfa.Q) {
+		t.Errorf("steps created %d states, the NFA has %d", total, len(nfa.Q))
+	}
+	if got := steps[5].String(); got != "ConcatenateNFAs on (a|b)*c: no new states, 1 ε-edges added (q7→q8)" {
+		t.Errorf("unexpected step description %q", got)
+	}
+
+	// Snapshots are not affected by later steps
+	if first := steps[0].NFA; len(first.Q) != 2 || !first.Accept("a") || first.Accept("b") {
+		t.Errorf("first snapshot changed: %v", first.Q)
+	}
+	if last := steps[len(steps)-1].NFA; !last.Accept("abac") || last.Accept("ab") {
+		t.Error("last snapshot should accept the whole pattern")
+	}
+
+	// Parsing again starts a new log
+	if _, err := r.ParseAST(); err != nil || len(r.Steps()) != 0 {
+		t.Errorf("ParseAST should reset the steps, got %d", len(r.Steps()))
+	}
+
+	// Copies made by + and {m,n} get fresh states, so no state or edge is
+	// recorded twice
+	for _, pattern := range []string{"(a|b)(c|d)E+G?", "(ab)+c{2,3}"} {
+		r := NewRegex(pattern)
+		node, err := r.ParseAST()
+		if err != nil {
+			t.Fatal(err)
+		}
+		nfa := r.Compile(node)
+
+		seen := make(map[State]bool)
+		for _, q := range nfa.Q {
+			if seen[q] {
+				t.Errorf("'%s': state %s appears twice", pattern, q)
+			}
+			seen[q] = true
+		}
+		for _, step := range r.Steps() {
+			edges := make(map[EpsilonEdge]bool)
+			for _, e := range step.EpsilonEdges {
+				if edges[e] {
+					t.Errorf("'%s': %s lists %s twice", pattern, step.Helper, e)
+				}
+				edges[e] = true
+			}
+		}
+		for _, w := range []string{"acE", "bdEEG", "abcc", "ababccc"} {
+			if nfa.Accept(w) != regexp.MustCompile("^(?:"+pattern+")$").MatchString(w) {
+				t.Errorf("'%s' disagrees with regexp on %q", pattern, w)
+			}
+		}
+	}
+}
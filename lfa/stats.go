@@ -0,0 +1,267 @@
+package lfa
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Stats describes the structure of an automaton
+type Stats struct {
+	States       int
+	Transitions  int // labelled edges, one per target state
+	EpsilonEdges int
+	// Nondeterminism is the largest number of states reachable from one
+	// state by reading one rune, not counting epsilon moves
+	Nondeterminism int
+	Unreachable    []State // not reachable from an initial state
+	Dead           []State // no final state is reachable from them
+	// Components are the strongly connected components, in topological
+	// order from the initial states
+	Components [][]State
+	Complete   bool     // every state has a transition on every rune of Sigma
+	NotDFA     []string // why the automaton is not a complete DFA, see IsDFA
+}
+
+// reachable returns the states reachable from sources along edges, or
+// against them when backward is set
+func reachable(edges []edge, sources []State, backward bool) map[State]bool {
+	seen := make(map[State]bool)
+	for state := range shortestPaths(edges, sources, backward) {
+		seen[state] = true
+	}
+	return seen
+}
+
+// CheckDFA explains why the NFA is not a complete DFA: every reason is a
+// sentence about one state, and the list is empty when IsDFA holds
+func (n *NFA) CheckDFA() []string {
+	reasons := make([]string, 0)
+	states := append([]State{}, n.Q...)
+	sort.Strings(states)
+
+	for _, q := range states {
+		transitions, ok := n.Delta[q]
+		if !ok {
+			reasons = append(reasons, fmt.Sprintf("%s has no transitions", q))
+			continue
+		}
+
+		labels := make([]RuneRange, 0, len(transitions))
+		for symbol, destinations := range transitions {
+			if symbol.IsEpsilon() {
+				reasons = append(reasons, fmt.Sprintf("%s has an ε-transition", q))
+				continue
+			}
+			if len(destinations) != 1 {
+				reasons = append(reasons, fmt.Sprintf("%s has %d targets on %s", q, len(destinations), symbol))
+			}
+			labels = append(labels, symbol)
+		}
+
+		for _, piece := range splitRanges(labels) {
+			count := 0
+			for _, label := range labels {
+				if label.Contains(piece.Lo) {
+					count++
+				}
+			}
+			if count > 1 {
+				reasons = append(reasons, fmt.Sprintf("%s has overlapping labels on %s", q, piece))
+			}
+		}
+
+		if missing := Alphabet(labels).Complement(n.Sigma); len(missing) != 0 {
+			reasons = append(reasons, fmt.Sprintf("%s has no transition on %s", q, missing))
+		}
+	}
+
+	return reasons
+}
+
+// Stats computes the structural statistics of the NFA
+func (n *NFA) Stats() Stats {
+	edges := n.sortedEdges()
+	s := Stats{States: len(n.Q), NotDFA: n.CheckDFA()}
+
+	labels := make([]RuneRange, 0)
+	for _, e := range edges {
+		if e.symbol.IsEpsilon() {
+			s.EpsilonEdges++
+		} else {
+			s.Transitions++
+			labels = append(labels, e.symbol)
+		}
+	}
+
+	pieces := splitRanges(append(labels, n.Sigma...))
+	s.Complete = true
+	for _, q := range n.Q {
+		for _, piece := range pieces {
+			targets := len(n.Delta.LookupRune(q, piece.Lo))
+			s.Nondeterminism = max(s.Nondeterminism, targets)
+			if targets == 0 && n.Sigma.Contains(piece.Lo) {
+				s.Complete = false
+			}
+		}
+	}
+
+	forward := reachable(edges, n.Q0, false)
+	backward := reachable(edges, n.F, true)
+	for _, q := range n.Q {
+		if !forward[q] {
+			s.Unreachable = append(s.Unreachable, q)
+		}
+		if !backward[q] {
+			s.Dead = append(s.Dead, q)
+		}
+	}
+	sort.Strings(s.Unreachable)
+	sort.Strings(s.Dead)
+
+	s.Components = n.components(edges)
+	return s
+}
+
+// components computes the strongly connected components with Tarjan's
+// algorithm. Tarjan's algorithm finds them in reverse topological order;
+// they are returned reversed, each one sorted.
+func (n *NFA) components(edges []edge) [][]State {
+	out := make(map[State][]State)
+	for _, e := range edges {
+		out[e.from] = append(out[e.from], e.to)
+	}
+
+	index := make(map[State]int)
+	low := make(map[State]int)
+	onStack := make(map[State]bool)
+	stack := make([]State, 0)
+	components := make([][]State, 0)
+
+	var visit func(q State)
+	visit = func(q State) {
+		index[q] = len(index)
+		low[q] = index[q]
+		stack = append(stack, q)
+		onStack[q] = true
+
+		for _, next := range out[q] {
+			if _, seen := index[next]; !seen {
+				visit(next)
+				low[q] = min(low[q], low[next])
+			} else if onStack[next] {
+				low[q] = min(low[q], index[next])
+			}
+		}
+
+		if low[q] == index[q] {
+			component := make([]State, 0)
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == q {
+					break
+				}
+			}
+			sort.Strings(component)
+			components = append(components, component)
+		}
+	}
+
+	// Initial states first, so the order follows the automaton
+	roots := append([]State{}, n.Q0...)
+	others := append([]State{}, n.Q...)
+	sort.Strings(others)
+	for _, q := range append(roots, others...) {
+		if _, seen := index[q]; !seen {
+			visit(q)
+		}
+	}
+
+	for i, j := 0, len(components)-1; i < j; i, j = i+1, j-1 {
+		components[i], components[j] = components[j], components[i]
+	}
+	return components
+}
+
+// Stats computes the structural statistics of the DFA
+func (d *DFA) Stats() Stats {
+	return d.ToNFA().Stats()
+}
+
+// String renders the statistics as a short report
+func (s Stats) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "states: %d, transitions: %d, ε-edges: %d\n", s.States, s.Transitions, s.EpsilonEdges)
+	fmt.Fprintf(&sb, "nondeterminism degree: %d, complete: %v\n", s.Nondeterminism, s.Complete)
+	fmt.Fprintf(&sb, "unreachable: %v, dead: %v\n", s.Unreachable, s.Dead)
+
+	cycles := make([]string, 0)
+	for _, c := range s.Components {
+		if len(c) > 1 {
+			cycles = append(cycles, "{"+strings.Join(c, ",")+"}")
+		}
+	}
+	fmt.Fprintf(&sb, "strongly connected components: %d, with several states: %s\n", len(s.Components), strings.Join(cycles, " "))
+
+	if len(s.NotDFA) == 0 {
+		sb.WriteString("complete DFA: yes")
+	} else {
+		sb.WriteString("complete DFA: no\n  " + strings.Join(s.NotDFA, "\n  "))
+	}
+	return sb.String()
+}
+
+// Trim removes the states that are unreachable or from which no final state
+// can be reached, along with their transitions. The initial states are kept
+// even when the language is empty.
+func (n *NFA) Trim() *NFA {
+	edges := n.sortedEdges()
+	forward := reachable(edges, n.Q0, false)
+	backward := reachable(edges, n.F, true)
+	useful := func(q State) bool {
+		return contains(n.Q0, q) || forward[q] && backward[q]
+	}
+
+	states := make([]State, 0)
+	for _, q := range n.Q {
+		if useful(q) {
+			states = append(states, q)
+		}
+	}
+
+	delta := make(DeltaNfa)
+	for _, e := range edges {
+		if useful(e.from) && useful(e.to) && backward[e.to] {
+			delta.Add(e.from, e.symbol, NewSetState(e.to))
+		}
+	}
+
+	final := make([]State, 0)
+	for _, f := range n.F {
+		if useful(f) {
+			final = append(final, f)
+		}
+	}
+
+	return NewNFA(states, append(Alphabet{}, n.Sigma...), delta, append([]State{}, n.Q0...), final)
+}
+
+// Trim removes the unreachable states and the dead states of the DFA, which
+// makes it partial. The initial state is always kept.
+func (d *DFA) Trim() *DFA {
+	trimmed := d.ToNFA().Trim()
+
+	delta := make(DeltaDFA)
+	for from, transitions := range trimmed.Delta {
+		for symbol, to := range transitions {
+			for q := range to {
+				delta.Add(from, symbol, q)
+			}
+		}
+	}
+
+	return NewDFA(trimmed.Q, append(Alphabet{}, d.Sigma...), delta, d.Q0, trimmed.F)
+}
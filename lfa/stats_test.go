@@ -0,0 +1,113 @@
+package lfa
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// statsNFA has an unreachable state q4 and a dead state q3
+func statsNFA() *NFA {
+	delta := make(DeltaNfa)
+	delta.Add("q0", Sym('a'), NewSetState("q1", "q3"))
+	delta.Add("q0", Epsilon, NewSetState("q2"))
+	delta.Add("q1", Sym('b'), NewSetState("q0"))
+	delta.Add("q2", Sym('b'), NewSetState("q2"))
+	delta.Add("q3", Sym('a'), NewSetState("q3"))
+	delta.Add("q4", Sym('a'), NewSetState("q2"))
+
+	return NewNFA([]State{"q0", "q1", "q2", "q3", "q4"}, NewAlphabet('a', 'b'), delta, []State{"q0"}, []State{"q2"})
+}
+
+func TestNFAStats(t *testing.T) {
+	s := statsNFA().Stats()
+
+	if s.States != 5 || s.Transitions != 6 || s.EpsilonEdges != 1 {
+		t.Errorf("unexpected counts: %d states, %d transitions, %d ε-edges", s.States, s.Transitions, s.EpsilonEdges)
+	}
+	if s.Nondeterminism != 2 {
+		t.Errorf("expected nondeterminism degree 2, got %d", s.Nondeterminism)
+	}
+	if !reflect.DeepEqual(s.Unreachable, []State{"q4"}) || !reflect.DeepEqual(s.Dead, []State{"q3"}) {
+		t.Errorf("unreachable %v, dead %v", s.Unreachable, s.Dead)
+	}
+	if s.Complete {
+		t.Error("q1 has no transition on a, the NFA is not complete")
+	}
+
+	want := [][]State{{"q4"}, {"q0", "q1"}, {"q3"}, {"q2"}}
+	if !reflect.DeepEqual(s.Components, want) {
+		t.Errorf("components %v", s.Components)
+	}
+	// Components come in topological order: no edge goes back
+	position := make(map[State]int)
+	for i, c := range s.Components {
+		for _, q := range c {
+			position[q] = i
+		}
+	}
+	for _, e := range statsNFA().sortedEdges() {
+		if position[e.to] < position[e.from] {
+			t.Errorf("edge %s → %s goes back in %v", e.from, e.to, s.Components)
+		}
+	}
+
+	report := s.String()
+	for _, reason := range []string{"q0 has an ε-transition", "q0 has 2 targets on a", "q1 has no transition on a"} {
+		if !contains(s.NotDFA, reason) || !strings.Contains(report, reason) {
+			t.Errorf("missing reason %q in %v", reason, s.NotDFA)
+		}
+	}
+}
+
+func TestIsDFAReasons(t *testing.T) {
+	dfa, _ := CreateNFAFromRegex("(a|b)*abb")
+	complete := dfa.ToDFA().Complement(NewAlphabet('a', 'b')).ToNFA()
+	assert(t, complete.IsDFA(), "a complemented DFA is complete")
+	assert(t, len(complete.CheckDFA()) == 0, "no reasons for a complete DFA")
+
+	delta := make(DeltaNfa)
+	delta.Add("q0", Range('a', 'c'), NewSetState("q0"))
+	delta.Add("q0", Range('b', 'd'), NewSetState("q0"))
+	overlapping := NewNFA([]State{"q0"}, Alphabet{Range('a', 'd')}, delta, []State{"q0"}, []State{"q0"})
+	if got := overlapping.CheckDFA(); !reflect.DeepEqual(got, []string{"q0 has overlapping labels on [bc]"}) {
+		t.Errorf("got %v", got)
+	}
+	assert(t, !overlapping.IsDFA(), "overlapping labels are nondeterministic")
+}
+
+func TestTrim(t *testing.T) {
+	n := statsNFA()
+	trimmed := n.Trim()
+
+	if len(trimmed.Q) != 3 || contains(trimmed.Q, "q3") || contains(trimmed.Q, "q4") {
+		t.Errorf("expected q0, q1 and q2, got %v", trimmed.Q)
+	}
+	s := trimmed.Stats()
+	if len(s.Unreachable) != 0 || len(s.Dead) != 0 {
+		t.Errorf("trimmed NFA still has unreachable %v or dead %v states", s.Unreachable, s.Dead)
+	}
+	for _, w := range allWords("ab", 6) {
+		if n.Accept(w) != trimmed.Accept(w) {
+			t.Fatalf("trimming changed the language on %q", w)
+		}
+	}
+
+	// A complete DFA loses its sink when trimmed
+	dfa := n.ToDFA().Complement(NewAlphabet('a', 'b')).Complement(NewAlphabet('a', 'b'))
+	trimmedDFA := dfa.Trim()
+	if len(trimmedDFA.Q) >= len(dfa.Q) || trimmedDFA.Stats().Complete {
+		t.Errorf("the sink of %v should be removed, got %v", dfa.Q, trimmedDFA.Q)
+	}
+	for _, w := range allWords("ab", 6) {
+		if dfa.Accept(w) != trimmedDFA.Accept(w) {
+			t.Fatalf("trimming changed the DFA language on %q", w)
+		}
+	}
+
+	// The initial state stays when the language is empty
+	empty, _ := CreateNFAFromRegex("a[]")
+	if got := empty.Trim(); len(got.Q) != 1 || len(got.F) != 0 {
+		t.Errorf("expected only the initial state, got %v", got.Q)
+	}
+}
@@ -0,0 +1,299 @@
+package lfa
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// sinkState stands for the missing transitions of a partial DFA in the
+// distinguishability table
+const sinkState = "∅"
+
+// pairMark records when and why two states were told apart
+type pairMark struct {
+	round  int
+	suffix string
+}
+
+// DistinguishabilityTable is the result of the Myhill–Nerode table-filling
+// algorithm: for every pair of states, whether some suffix leads one of them
+// to a final state and the other not. Round 0 marks the pairs made of a
+// final and a non-final state, and round k marks a pair when a symbol leads
+// it to a pair marked in an earlier round, so the round of a pair is the
+// length of its shortest distinguishing suffix. The pairs left unmarked are
+// the equivalent states that minimization merges.
+type DistinguishabilityTable struct {
+	States []State
+	Rounds int
+	index  map[State]int
+	marks  map[[2]int]pairMark
+}
+
+// TableFilling computes the distinguishability table of the DFA. A partial
+// DFA is completed with the sink state "∅", which takes part in the table.
+func (d *DFA) TableFilling() *DistinguishabilityTable {
+	states := append([]State{}, d.Q...)
+	sort.Strings(states)
+
+	pieces := splitRanges(append(append([]RuneRange{}, d.Sigma...), d.labels()...))
+	complete := true
+	for _, q := range states {
+		for _, piece := range pieces {
+			if d.Delta.LookupRune(q, piece.Lo) == "" {
+				complete = false
+			}
+		}
+	}
+	if !complete {
+		states = append(states, sinkState)
+	}
+
+	t := &DistinguishabilityTable{
+		States: states,
+		index:  make(map[State]int),
+		marks:  make(map[[2]int]pairMark),
+	}
+	for i, q := range states {
+		t.index[q] = i
+	}
+
+	next := func(q State, c rune) State {
+		if q == sinkState {
+			return sinkState
+		}
+		if to := d.Delta.LookupRune(q, c); to != "" {
+			return to
+		}
+		return sinkState
+	}
+
+	for i := range states {
+		for j := i + 1; j < len(states); j++ {
+			if contains(d.F, states[i]) != contains(d.F, states[j]) {
+				t.marks[[2]int{i, j}] = pairMark{round: 0, suffix: ""}
+			}
+		}
+	}
+
+	for round := 1; ; round++ {
+		marked := make(map[[2]int]pairMark)
+		for i := range states {
+			for j := i + 1; j < len(states); j++ {
+				if _, ok := t.marks[[2]int{i, j}]; ok {
+					continue
+				}
+				for _, piece := range pieces {
+					p, q := next(states[i], piece.Lo), next(states[j], piece.Lo)
+					if m, ok := t.mark(p, q); ok {
+						marked[[2]int{i, j}] = pairMark{round: round, suffix: string(piece.Lo) + m.suffix}
+						break
+					}
+				}
+			}
+		}
+
+		if len(marked) == 0 {
+			break
+		}
+		for pair, m := range marked {
+			t.marks[pair] = m
+		}
+		t.Rounds = round
+	}
+
+	return t
+}
+
+// labels returns the transition labels of the DFA
+func (d *DFA) labels() []RuneRange {
+	labels := make([]RuneRange, 0)
+	for _, transitions := range d.Delta {
+		for symbol := range transitions {
+			labels = append(labels, symbol)
+		}
+	}
+	return labels
+}
+
+func (t *DistinguishabilityTable) mark(p, q State) (pairMark, bool) {
+	i, j := t.index[p], t.index[q]
+	if i > j {
+		i, j = j, i
+	}
+	m, ok := t.marks[[2]int{i, j}]
+	return m, ok
+}
+
+// Distinguishing returns the shortest suffix telling p and q apart and the
+// round it was found in, or false when the states are equivalent
+func (t *DistinguishabilityTable) Distinguishing(p, q State) (string, int, bool) {
+	m, ok := t.mark(p, q)
+	return m.suffix, m.round, ok
+}
+
+// Classes returns the classes of equivalent states, each sorted, ordered by
+// their first state
+func (t *DistinguishabilityTable) Classes() [][]State {
+	classes := make([][]State, 0)
+	assigned := make(map[State]bool)
+	for i, p := range t.States {
+		if assigned[p] {
+			continue
+		}
+		class := []State{p}
+		assigned[p] = true
+		for _, q := range t.States[i+1:] {
+			if _, ok := t.mark(p, q); !ok && !assigned[q] {
+				class = append(class, q)
+				assigned[q] = true
+			}
+		}
+		classes = append(classes, class)
+	}
+	return classes
+}
+
+// cell renders a pair of the table as "round:suffix", or "=" when the states
+// are equivalent
+func (t *DistinguishabilityTable) cell(p, q State) string {
+	m, ok := t.mark(p, q)
+	if !ok {
+		return "="
+	}
+	if m.suffix == "" {
+		return fmt.Sprintf("%d:ε", m.round)
+	}
+	return fmt.Sprintf("%d:%s", m.round, m.suffix)
+}
+
+// String renders the lower half of the table, one row per state from the
+// second one, each cell holding the marking round and the suffix. A DFA
+// without states gives an empty table.
+func (t *DistinguishabilityTable) String() string {
+	if len(t.States) == 0 {
+		return ""
+	}
+
+	width := 0
+	for _, p := range t.States {
+		width = max(width, len([]rune(p)))
+		for _, q := range t.States {
+			if p != q {
+				width = max(width, len([]rune(t.cell(p, q))))
+			}
+		}
+	}
+	pad := func(s string) string {
+		return s + strings.Repeat(" ", width-len([]rune(s)))
+	}
+
+	var sb strings.Builder
+	for i, p := range t.States[1:] {
+		sb.WriteString(pad(p) + " |")
+		for _, q := range t.States[:i+1] {
+			sb.WriteString(" " + pad(t.cell(p, q)))
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString(pad("") + " |")
+	for _, q := range t.States[:len(t.States)-1] {
+		sb.WriteString(" " + pad(q))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// Markdown renders the lower half of the table as a Markdown table, empty
+// for a DFA without states
+func (t *DistinguishabilityTable) Markdown() string {
+	if len(t.States) == 0 {
+		return ""
+	}
+
+	columns := t.States[:len(t.States)-1]
+
+	var sb strings.Builder
+	sb.WriteString("| |")
+	for _, q := range columns {
+		sb.WriteString(" " + q + " |")
+	}
+	sb.WriteString("\n|---|")
+	for range columns {
+		sb.WriteString("---|")
+	}
+	sb.WriteString("\n")
+
+	for i, p := range t.States[1:] {
+		sb.WriteString("| " + p + " |")
+		for j, q := range columns {
+			if j <= i {
+				sb.WriteString(" " + t.cell(p, q) + " |")
+			} else {
+				sb.WriteString(" |")
+			}
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// Minimize builds the minimal partial DFA of the same language by merging
+// the equivalent states of the distinguishability table. Unreachable states
+// and dead states, from which no final state can be reached, are dropped
+// like the sink, except for the initial state. Merged states are named like
+// "{q1,q2}" while the others keep their name.
+func (d *DFA) Minimize() *DFA {
+	t := d.TableFilling()
+	edges := d.ToNFA().sortedEdges()
+	forward := reachable(edges, []State{d.Q0}, false)
+	backward := reachable(edges, d.F, true)
+
+	name := make(map[State]State)
+	kept := make([][]State, 0)
+	for _, class := range t.Classes() {
+		// Equivalent states are all dead or all alive, like the sink
+		reached, alive := false, false
+		for _, q := range class {
+			reached = reached || forward[q]
+			alive = alive || backward[q]
+		}
+		if !contains(class, d.Q0) && (!reached || !alive) {
+			continue
+		}
+
+		members := make([]State, 0, len(class))
+		for _, q := range class {
+			if q != sinkState {
+				members = append(members, q)
+			}
+		}
+		merged := members[0]
+		if len(members) > 1 {
+			merged = NewSetState(members...).toState()
+		}
+		for _, q := range members {
+			name[q] = merged
+		}
+		kept = append(kept, members)
+	}
+
+	pieces := splitRanges(append(append([]RuneRange{}, d.Sigma...), d.labels()...))
+	states := make([]State, 0, len(kept))
+	final := make([]State, 0)
+	delta := make(DeltaDFA)
+	for _, class := range kept {
+		from := name[class[0]]
+		states = append(states, from)
+		if contains(d.F, class[0]) {
+			final = append(final, from)
+		}
+		for _, piece := range pieces {
+			if to, ok := name[d.Delta.LookupRune(class[0], piece.Lo)]; ok {
+				delta.Add(from, piece, to)
+			}
+		}
+	}
+
+	return NewDFA(states, append(Alphabet{}, d.Sigma...), delta, name[d.Q0], final)
+}
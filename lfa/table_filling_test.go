@@ -0,0 +1,196 @@
+package lfa
+
+import (
+	"strings"
+	"testing"
+)
+
+// textbookDFA is the classic example with equivalent states a ≡ e, b ≡ h
+// and the unreachable state d
+func textbookDFA(t *testing.T) *DFA {
+	dfa, err := NewDFABuilder().
+		Alphabet(Sym('0'), Sym('1')).
+		State("a", "b", "c", "d", "e", "f", "g", "h").
+		Start("a").
+		Final("c").
+		Edge("a", Sym('0'), "b").Edge("a", Sym('1'), "f").
+		Edge("b", Sym('0'), "g").Edge("b", Sym('1'), "c").
+		Edge("c", Sym('0'), "a").Edge("c", Sym('1'), "c").
+		Edge("d", Sym('0'), "c").Edge("d", Sym('1'), "g").
+		Edge("e", Sym('0'), "h").Edge("e", Sym('1'), "f").
+		Edge("f", Sym('0'), "c").Edge("f", Sym('1'), "g").
+		Edge("g", Sym('0'), "g").Edge("g", Sym('1'), "e").
+		Edge("h", Sym('0'), "g").Edge("h", Sym('1'), "c").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dfa
+}
+
+// acceptsFrom runs the DFA from state q, treating a missing transition or
+// the sink as a rejection
+func acceptsFrom(d *DFA, q State, word string) bool {
+	for _, c := range word {
+		if q == sinkState {
+			return false
+		}
+		q = d.Delta.LookupRune(q, c)
+		if q == "" {
+			return false
+		}
+	}
+	return q != sinkState && contains(d.F, q)
+}
+
+func TestTableFilling(t *testing.T) {
+	dfa := textbookDFA(t)
+	table := dfa.TableFilling()
+
+	classes := table.Classes()
+	want := [][]State{{"a", "e"}, {"b", "h"}, {"c"}, {"d", "f"}, {"g"}}
+	if len(classes) != len(want) {
+		t.Fatalf("expected classes %v, got %v", want, classes)
+	}
+	for i := range want {
+		if strings.Join(classes[i], ",") != strings.Join(want[i], ",") {
+			t.Errorf("expected classes %v, got %v", want, classes)
+		}
+	}
+
+	// Every suffix distinguishes its pair, and none shorter does
+	for i, p := range table.States {
+		for _, q := range table.States[i+1:] {
+			suffix, round, ok := table.Distinguishing(p, q)
+			if !ok {
+				for _, w := range allWords("01", 5) {
+					if acceptsFrom(dfa, p, w) != acceptsFrom(dfa, q, w) {
+						t.Fatalf("%s and %s are marked equivalent but %q tells them apart", p, q, w)
+					}
+				}
+				continue
+			}
+			if len(suffix) != round {
+				t.Errorf("%s,%s: suffix %q found in round %d", p, q, suffix, round)
+			}
+			if acceptsFrom(dfa, p, suffix) == acceptsFrom(dfa, q, suffix) {
+				t.Errorf("%q does not distinguish %s and %s", suffix, p, q)
+			}
+			for _, w := range allWords("01", round-1) {
+				if round > 0 && acceptsFrom(dfa, p, w) != acceptsFrom(dfa, q, w) {
+					t.Errorf("%s,%s: %q is shorter than %q", p, q, w, suffix)
+				}
+			}
+		}
+	}
+
+	if suffix, round, _ := table.Distinguishing("a", "g"); suffix != "01" || round != 2 {
+		t.Errorf("a,g: expected 01 in round 2, got %q in round %d", suffix, round)
+	}
+	if table.Rounds != 2 {
+		t.Errorf("expected 2 rounds, got %d", table.Rounds)
+	}
+
+	text := table.String()
+	if !strings.Contains(text, "\nh    | 1:1  =    0:ε") || !strings.HasSuffix(text, "     | a    b    c    d    e    f    g   \n") {
+		t.Errorf("unexpected text table:\n%s", text)
+	}
+	markdown := table.Markdown()
+	if !strings.HasPrefix(markdown, "| | a | b | c | d | e | f | g |\n|---|") || !strings.Contains(markdown, "| e | = | 1:1 | 0:ε | 1:0 | |") {
+		t.Errorf("unexpected Markdown table:\n%s", markdown)
+	}
+}
+
+func TestMinimizeMatchesTable(t *testing.T) {
+	dfa := textbookDFA(t)
+	minimal := dfa.Minimize()
+
+	// d is unreachable but equivalent to f, so every class remains
+	if len(minimal.Q) != 5 || minimal.Q0 != "{a,e}" || !contains(minimal.Q, "{d,f}") {
+		t.Errorf("unexpected minimal DFA %v starting at %s", minimal.Q, minimal.Q0)
+	}
+	for _, w := range allWords("01", 8) {
+		if dfa.Accept(w) != minimal.Accept(w) {
+			t.Fatalf("minimization changed the language on %q", w)
+		}
+	}
+
+	// The minimal DFA has no equivalent states left
+	if classes := minimal.TableFilling().Classes(); len(classes) != len(minimal.Q) {
+		t.Errorf("minimal DFA still has equivalent states: %v", classes)
+	}
+}
+
+func TestMinimizeCompleteDFA(t *testing.T) {
+	// d is a dead state rather than a missing transition
+	dfa, err := NewDFABuilder().
+		Alphabet(Sym('a'), Sym('b')).
+		State("s", "f", "d").
+		Start("s").
+		Final("f").
+		Edge("s", Sym('a'), "f").Edge("s", Sym('b'), "d").
+		Edge("f", Range('a', 'b'), "d").
+		Edge("d", Range('a', 'b'), "d").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := dfa.Minimize(); len(got.Q) != 2 || contains(got.Q, "d") {
+		t.Errorf("expected s and f, got %v", got.Q)
+	}
+
+	// The same language minimizes to the same size, complete or not
+	alphabet := NewAlphabet('a', 'b')
+	for _, pattern := range []string{"(a|b)*abb", "a+b?", "a[]"} {
+		nfa, _ := CreateNFAFromRegex(pattern)
+		partial := nfa.ToDFA()
+		complete := partial.Complement(alphabet).Complement(alphabet)
+		if !complete.Stats().Complete {
+			t.Fatalf("'%s': expected a complete DFA, got %v", pattern, complete.Q)
+		}
+		a, b := partial.Minimize(), complete.Minimize()
+		if len(a.Q) != len(b.Q) {
+			t.Errorf("'%s': minimal DFAs have %d and %d states", pattern, len(a.Q), len(b.Q))
+		}
+		for _, w := range allWords("ab", 6) {
+			if b.Accept(w) != partial.Accept(w) {
+				t.Fatalf("'%s': minimization changed the language on %q", pattern, w)
+			}
+		}
+	}
+}
+
+func TestTableFillingWithoutStates(t *testing.T) {
+	table := NewDFA([]State{}, Alphabet{}, make(DeltaDFA), "", []State{}).TableFilling()
+	if table.String() != "" || table.Markdown() != "" {
+		t.Errorf("expected empty tables, got %q and %q", table.String(), table.Markdown())
+	}
+}
+
+func TestMinimizeConstructionsAgree(t *testing.T) {
+	for _, pattern := range []string{"(a|b)*abb", "a+b?(ab)*", "(a|ab)(b|)", "[ab]{2,3}b*", "a[]", "()"} {
+		r := NewRegex(pattern)
+		r.SetAlphabet(NewAlphabet('a', 'b'))
+		node, err := r.ParseAST()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		subset := r.Compile(node).ToDFA().Minimize()
+		derivative := r.CompileDFA(node).Minimize()
+		followpos, err := r.PositionDFA(node)
+		if err != nil {
+			t.Fatal(err)
+		}
+		positions := followpos.Minimize()
+
+		if len(subset.Q) != len(derivative.Q) || len(subset.Q) != len(positions.Q) {
+			t.Errorf("'%s': minimal DFAs have %d, %d and %d states", pattern, len(subset.Q), len(derivative.Q), len(positions.Q))
+		}
+		for _, w := range allWords("ab", 6) {
+			if subset.Accept(w) != derivative.Accept(w) || subset.Accept(w) != positions.Accept(w) {
+				t.Fatalf("'%s': minimal DFAs disagree on %q", pattern, w)
+			}
+		}
+	}
+}
@@ -1,3 +1,233 @@
 package lfa
 
+import (
+	"sort"
+	"strings"
+)
 
+// RuneRange is an inclusive range of runes [Lo, Hi] used as a transition label.
+// A single symbol is represented by a range with Lo == Hi.
+type RuneRange struct {
+	Lo rune
+	Hi rune
+}
+
+// Epsilon represents an epsilon (ε) transition in an NFA.
+// It uses negative bounds so it can never collide with a real rune.
+var Epsilon = RuneRange{Lo: -1, Hi: -1}
+
+// Sym returns the range containing only the rune r
+func Sym(r rune) RuneRange {
+	return RuneRange{Lo: r, Hi: r}
+}
+
+// Range returns the range of runes from lo to hi inclusive
+func Range(lo, hi rune) RuneRange {
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	return RuneRange{Lo: lo, Hi: hi}
+}
+
+// IsEpsilon reports whether the range is the epsilon marker
+func (r RuneRange) IsEpsilon() bool {
+	return r == Epsilon
+}
+
+// Contains reports whether c falls inside the range
+func (r RuneRange) Contains(c rune) bool {
+	return !r.IsEpsilon() && r.Lo <= c && c <= r.Hi
+}
+
+// Size returns the number of runes covered by the range
+func (r RuneRange) Size() int {
+	if r.IsEpsilon() {
+		return 0
+	}
+	return int(r.Hi-r.Lo) + 1
+}
+
+// String renders the range as a transition label: "a", "[a-z]" or "ε"
+func (r RuneRange) String() string {
+	switch {
+	case r.IsEpsilon():
+		return "ε"
+	case r.Lo == r.Hi:
+		return string(r.Lo)
+	}
+	return "[" + r.classBody() + "]"
+}
+
+// classBody renders the range without brackets, as it appears inside a class
+func (r RuneRange) classBody() string {
+	switch {
+	case r.Lo == r.Hi:
+		return string(r.Lo)
+	case r.Hi == r.Lo+1:
+		return string(r.Lo) + string(r.Hi)
+	}
+	return string(r.Lo) + "-" + string(r.Hi)
+}
+
+// intersect returns the runes common to both ranges, if any
+func (r RuneRange) intersect(other RuneRange) (RuneRange, bool) {
+	if r.IsEpsilon() || other.IsEpsilon() {
+		return Epsilon, r == other
+	}
+	lo, hi := max(r.Lo, other.Lo), min(r.Hi, other.Hi)
+	if lo > hi {
+		return RuneRange{}, false
+	}
+	return RuneRange{Lo: lo, Hi: hi}, true
+}
+
+// Alphabet is a set of runes stored as a list of ranges
+type Alphabet []RuneRange
+
+// NewAlphabet creates an alphabet made of the given single symbols
+func NewAlphabet(symbols ...rune) Alphabet {
+	a := make(Alphabet, 0, len(symbols))
+	for _, s := range symbols {
+		a = append(a, Sym(s))
+	}
+	return a
+}
+
+// Contains reports whether any range of the alphabet contains c
+func (a Alphabet) Contains(c rune) bool {
+	for _, r := range a {
+		if r.Contains(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// Runes expands the alphabet into the individual runes it covers
+func (a Alphabet) Runes() []rune {
+	runes := make([]rune, 0)
+	for _, r := range a.Normalize() {
+		for c := r.Lo; c <= r.Hi; c++ {
+			runes = append(runes, c)
+		}
+	}
+	return runes
+}
+
+// Normalize returns the alphabet as sorted, non-overlapping, merged ranges
+// without the epsilon marker
+func (a Alphabet) Normalize() Alphabet {
+	ranges := make(Alphabet, 0, len(a))
+	for _, r := range a {
+		if !r.IsEpsilon() {
+			ranges = append(ranges, r)
+		}
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].Lo < ranges[j].Lo
+	})
+
+	merged := make(Alphabet, 0, len(ranges))
+	for _, r := range ranges {
+		if n := len(merged); n > 0 && r.Lo <= merged[n-1].Hi+1 {
+			if r.Hi > merged[n-1].Hi {
+				merged[n-1].Hi = r.Hi
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+
+	return merged
+}
+
+// Complement returns the runes of universe that are not in a
+func (a Alphabet) Complement(universe Alphabet) Alphabet {
+	result := make(Alphabet, 0)
+	excluded := a.Normalize()
+
+	for _, u := range universe.Normalize() {
+		lo := u.Lo
+		for _, e := range excluded {
+			if e.Hi < lo || e.Lo > u.Hi {
+				continue
+			}
+			if e.Lo > lo {
+				result = append(result, RuneRange{Lo: lo, Hi: e.Lo - 1})
+			}
+			lo = e.Hi + 1
+		}
+		if lo <= u.Hi {
+			result = append(result, RuneRange{Lo: lo, Hi: u.Hi})
+		}
+	}
+
+	return result
+}
+
+// String renders the alphabet as a character class, e.g. "[a-z0-9]"
+func (a Alphabet) String() string {
+	n := a.Normalize()
+	if len(n) == 1 {
+		return n[0].String()
+	}
+
+	var sb strings.Builder
+	sb.WriteString("[")
+	for _, r := range n {
+		sb.WriteString(r.classBody())
+	}
+	sb.WriteString("]")
+	return sb.String()
+}
+
+// splitRanges partitions the given ranges into disjoint pieces such that
+// every input range is exactly a union of pieces. This is what lets subset
+// construction work on ranges instead of individual symbols.
+func splitRanges(ranges []RuneRange) []RuneRange {
+	bounds := make([]rune, 0, 2*len(ranges))
+	for _, r := range ranges {
+		if r.IsEpsilon() {
+			continue
+		}
+		bounds = append(bounds, r.Lo, r.Hi+1)
+	}
+	if len(bounds) == 0 {
+		return nil
+	}
+
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+
+	pieces := make([]RuneRange, 0)
+	for i := 0; i+1 < len(bounds); i++ {
+		lo, hi := bounds[i], bounds[i+1]-1
+		if lo > hi {
+			continue
+		}
+		for _, r := range ranges {
+			if r.Contains(lo) {
+				pieces = append(pieces, RuneRange{Lo: lo, Hi: hi})
+				break
+			}
+		}
+	}
+
+	return pieces
+}
+
+// formatLabel joins several ranges leading to the same state into a single
+// edge label, escaping characters that are special inside DOT strings
+func formatLabel(ranges []RuneRange) string {
+	label := ""
+	if len(ranges) == 1 {
+		label = ranges[0].String()
+	} else {
+		label = Alphabet(ranges).String()
+	}
+	return dotEscape(label)
+}
+
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
@@ -0,0 +1,355 @@
+package lfa
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Rule is a production α → β whose left-hand side may be any non-empty
+// string of symbols containing at least one non-terminal
+type Rule struct {
+	LHS []string
+	RHS []string
+}
+
+func (r Rule) String() string {
+	rhs := strings.Join(r.RHS, " ")
+	if len(r.RHS) == 0 {
+		rhs = "ε"
+	}
+	return fmt.Sprintf("%s → %s", strings.Join(r.LHS, " "), rhs)
+}
+
+// UnrestrictedGrammar is a Type 0 grammar. Unlike GrammarV2 its productions
+// can rewrite a whole context, e.g. a A b → a B C b.
+type UnrestrictedGrammar struct {
+	NonTerminals map[string]bool
+	Terminals    map[string]bool
+	Rules        []Rule
+	StartSymbol  string
+	Epsilon      string
+}
+
+func NewUnrestrictedGrammar(startSymbol, epsilon string, nt, t []string) *UnrestrictedGrammar {
+	g := &UnrestrictedGrammar{
+		NonTerminals: make(map[string]bool),
+		Terminals:    make(map[string]bool),
+		Rules:        make([]Rule, 0),
+		StartSymbol:  startSymbol,
+		Epsilon:      epsilon,
+	}
+	for _, s := range nt {
+		g.NonTerminals[s] = true
+	}
+	for _, s := range t {
+		g.Terminals[s] = true
+	}
+	return g
+}
+
+// AddRule adds the production lhs → rhs. The epsilon symbol is dropped, so
+// both nil and {Epsilon} describe an empty right-hand side.
+func (g *UnrestrictedGrammar) AddRule(lhs, rhs []string) {
+	r := Rule{LHS: g.stripEpsilon(lhs), RHS: g.stripEpsilon(rhs)}
+	for _, existing := range g.Rules {
+		if slicesEqual(existing.LHS, r.LHS) && slicesEqual(existing.RHS, r.RHS) {
+			return
+		}
+	}
+	g.Rules = append(g.Rules, r)
+}
+
+func (g *UnrestrictedGrammar) stripEpsilon(symbols []string) []string {
+	out := make([]string, 0, len(symbols))
+	for _, s := range symbols {
+		if s != g.Epsilon {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// ToUnrestricted converts a context-free GrammarV2 to the general
+// representation
+func (g *GrammarV2) ToUnrestricted() *UnrestrictedGrammar {
+	u := NewUnrestrictedGrammar(g.StartSymbol, g.Epsilon, nil, nil)
+	for nt := range g.NonTerminals {
+		u.NonTerminals[nt] = true
+	}
+	for t := range g.Terminals {
+		u.Terminals[t] = true
+	}
+	for lhs, prods := range g.Productions {
+		for _, rhs := range prods {
+			u.AddRule([]string{lhs}, rhs)
+		}
+	}
+	return u
+}
+
+func (g *UnrestrictedGrammar) String() string {
+	var sb strings.Builder
+
+	sb.WriteString("Grammar:\n")
+	sb.WriteString(fmt.Sprintf("  Start Symbol: %s\n", g.StartSymbol))
+	sb.WriteString("  Productions:\n")
+	for _, r := range g.Rules {
+		sb.WriteString(fmt.Sprintf("\t%s\n", r))
+	}
+
+	return sb.String()
+}
+
+// startOnRightSide reports whether the start symbol occurs in some RHS,
+// which forbids the S → ε exception of Type 1 grammars
+func (g *UnrestrictedGrammar) startOnRightSide() bool {
+	for _, r := range g.Rules {
+		if contains(r.RHS, g.StartSymbol) {
+			return true
+		}
+	}
+	return false
+}
+
+// isStartEpsilon reports whether r is an S → ε rule allowed in a Type 1 grammar
+func (g *UnrestrictedGrammar) isStartEpsilon(r Rule) bool {
+	return len(r.LHS) == 1 && r.LHS[0] == g.StartSymbol && len(r.RHS) == 0 && !g.startOnRightSide()
+}
+
+// IsNoncontracting checks that no rule shortens the sentential form (|α| ≤ |β|
+// for every α → β), apart from S → ε when S never appears on a right side.
+// On failure the offending rule is returned.
+func (g *UnrestrictedGrammar) IsNoncontracting() (bool, *Rule) {
+	for i, r := range g.Rules {
+		if len(r.RHS) < len(r.LHS) && !g.isStartEpsilon(r) {
+			return false, &g.Rules[i]
+		}
+	}
+	return true, nil
+}
+
+// isContextSensitiveRule checks whether r has the form αAβ → αγβ with A a
+// non-terminal and γ non-empty
+func (g *UnrestrictedGrammar) isContextSensitiveRule(r Rule) bool {
+	if len(r.RHS) < len(r.LHS) {
+		return false
+	}
+
+	for i, sym := range r.LHS {
+		if !g.NonTerminals[sym] {
+			continue
+		}
+
+		alpha, beta := r.LHS[:i], r.LHS[i+1:]
+		if slicesEqual(r.RHS[:len(alpha)], alpha) && slicesEqual(r.RHS[len(r.RHS)-len(beta):], beta) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsContextSensitive checks that every rule has the form αAβ → αγβ with γ
+// non-empty, apart from S → ε when S never appears on a right side. On
+// failure the offending rule is returned.
+func (g *UnrestrictedGrammar) IsContextSensitive() (bool, *Rule) {
+	for i, r := range g.Rules {
+		if !g.isContextSensitiveRule(r) && !g.isStartEpsilon(r) {
+			return false, &g.Rules[i]
+		}
+	}
+	return true, nil
+}
+
+// IsContextFree checks that every left-hand side is a single non-terminal
+func (g *UnrestrictedGrammar) IsContextFree() (bool, *Rule) {
+	for i, r := range g.Rules {
+		if len(r.LHS) != 1 || !g.NonTerminals[r.LHS[0]] {
+			return false, &g.Rules[i]
+		}
+	}
+	return true, nil
+}
+
+// isLinearRule checks A → w or A → wB (right) / A → Bw (left), w terminals
+func (g *UnrestrictedGrammar) isLinearRule(r Rule, right bool) bool {
+	body := r.RHS
+	if len(body) > 0 {
+		edge := body[0]
+		if right {
+			edge = body[len(body)-1]
+		}
+		if g.NonTerminals[edge] {
+			if right {
+				body = body[:len(body)-1]
+			} else {
+				body = body[1:]
+			}
+		}
+	}
+
+	for _, sym := range body {
+		if !g.Terminals[sym] {
+			return false
+		}
+	}
+	return true
+}
+
+// IsRegular checks that the grammar is context-free and either entirely
+// right-linear or entirely left-linear. On failure the first rule breaking
+// right-linearity is returned.
+func (g *UnrestrictedGrammar) IsRegular() (bool, *Rule) {
+	if ok, r := g.IsContextFree(); !ok {
+		return false, r
+	}
+
+	var offending *Rule
+	for _, right := range []bool{true, false} {
+		linear := true
+		for i, r := range g.Rules {
+			if !g.isLinearRule(r, right) {
+				if right {
+					offending = &g.Rules[i]
+				}
+				linear = false
+				break
+			}
+		}
+		if linear {
+			return true, nil
+		}
+	}
+	return false, offending
+}
+
+// Classification is the result of placing a grammar in the Chomsky hierarchy.
+// Type 1 is assigned to noncontracting grammars, which generate exactly the
+// context-sensitive languages; ContextSensitive additionally tells whether
+// every rule has the strict αAβ → αγβ form.
+type Classification struct {
+	Type             int
+	Name             string
+	ContextSensitive bool
+	// Offending is the rule that keeps the grammar out of Type+1, nil for Type 3
+	Offending *Rule
+}
+
+func (c Classification) String() string {
+	s := fmt.Sprintf("Type %d: %s", c.Type, c.Name)
+	if c.Offending != nil {
+		s += fmt.Sprintf(" (not Type %d because of %s)", c.Type+1, c.Offending)
+	}
+	return s
+}
+
+// Classify returns the most restrictive Chomsky type of the grammar together
+// with the rule that prevents the next stricter one
+func (g *UnrestrictedGrammar) Classify() Classification {
+	contextSensitive, _ := g.IsContextSensitive()
+	c := Classification{ContextSensitive: contextSensitive}
+
+	regular, r3 := g.IsRegular()
+	contextFree, r2 := g.IsContextFree()
+	noncontracting, r1 := g.IsNoncontracting()
+
+	switch {
+	case regular:
+		c.Type, c.Name = 3, "Regular Grammar"
+	case contextFree:
+		c.Type, c.Name, c.Offending = 2, "Context-Free Grammar", r3
+	case noncontracting && contextSensitive:
+		c.Type, c.Name, c.Offending = 1, "Context-Sensitive Grammar", r2
+	case noncontracting:
+		c.Type, c.Name, c.Offending = 1, "Noncontracting Grammar", r2
+	default:
+		c.Type, c.Name, c.Offending = 0, "Unrestricted Grammar", r1
+	}
+
+	return c
+}
+
+// Derive searches breadth-first for a derivation of word from the start
+// symbol and returns the sentential forms along it, or nil if the word is not
+// in the language.
+//
+// For noncontracting grammars sentential forms longer than the word are
+// pruned, so the search space is finite, maxForms is ignored and the answer
+// is exact. For Type 0 grammars membership is only semi-decidable: the
+// search explores at most maxForms sentential forms and reports an error
+// when that bound is hit without finding a derivation.
+func (g *UnrestrictedGrammar) Derive(word []string, maxForms int) ([][]string, error) {
+	word = g.stripEpsilon(word)
+	noncontracting, _ := g.IsNoncontracting()
+
+	key := func(form []string) string {
+		return strings.Join(form, "\x00")
+	}
+
+	start := []string{g.StartSymbol}
+	parent := map[string][]string{key(start): nil}
+	forms := map[string][]string{key(start): start}
+	queue := [][]string{start}
+	target := key(word)
+
+	for explored := 0; len(queue) > 0; explored++ {
+		if !noncontracting && explored >= maxForms {
+			return nil, fmt.Errorf("derivation search gave up after %d sentential forms", maxForms)
+		}
+
+		form := queue[0]
+		queue = queue[1:]
+
+		if key(form) == target {
+			path := make([][]string, 0)
+			for k := target; ; {
+				path = append(path, forms[k])
+				prev := parent[k]
+				if prev == nil {
+					break
+				}
+				k = key(prev)
+			}
+			for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+				path[i], path[j] = path[j], path[i]
+			}
+			return path, nil
+		}
+
+		for _, r := range g.Rules {
+			for i := 0; i+len(r.LHS) <= len(form); i++ {
+				if !slicesEqual(form[i:i+len(r.LHS)], r.LHS) {
+					continue
+				}
+
+				next := make([]string, 0, len(form)-len(r.LHS)+len(r.RHS))
+				next = append(next, form[:i]...)
+				next = append(next, r.RHS...)
+				next = append(next, form[i+len(r.LHS):]...)
+
+				if noncontracting && len(next) > len(word) {
+					continue
+				}
+
+				k := key(next)
+				if _, seen := forms[k]; seen {
+					continue
+				}
+				forms[k] = next
+				parent[k] = form
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// Member reports whether the grammar derives word, see Derive for the
+// meaning of maxForms
+func (g *UnrestrictedGrammar) Member(word []string, maxForms int) (bool, error) {
+	path, err := g.Derive(word, maxForms)
+	if err != nil {
+		return false, err
+	}
+	return path != nil, nil
+}
@@ -0,0 +1,94 @@
+package lfa
+
+import (
+	"strings"
+	"testing"
+)
+
+func symbols(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "")
+}
+
+// makeAnBnCn is the classic noncontracting grammar for a^n b^n c^n (n ≥ 1)
+func makeAnBnCn() *UnrestrictedGrammar {
+	g := NewUnrestrictedGrammar("S", "ε", []string{"S", "B", "C"}, []string{"a", "b", "c"})
+	g.AddRule(symbols("S"), symbols("aSBC"))
+	g.AddRule(symbols("S"), symbols("aBC"))
+	g.AddRule(symbols("CB"), symbols("BC"))
+	g.AddRule(symbols("aB"), symbols("ab"))
+	g.AddRule(symbols("bB"), symbols("bb"))
+	g.AddRule(symbols("bC"), symbols("bc"))
+	g.AddRule(symbols("cC"), symbols("cc"))
+	return g
+}
+
+func TestUnrestrictedClassification(t *testing.T) {
+	c := makeAnBnCn().Classify()
+	t.Log(c)
+	assert(t, c.Type == 1, "a^n b^n c^n grammar should be Type 1")
+	assert(t, !c.ContextSensitive, "CB → BC is not of the form αAβ → αγβ")
+	assert(t, c.Offending != nil && c.Offending.String() == "C B → B C", "CB → BC should keep it out of Type 2")
+
+	strict := NewUnrestrictedGrammar("S", "ε", []string{"S", "A"}, []string{"a", "b"})
+	strict.AddRule(symbols("S"), symbols("aAb"))
+	strict.AddRule(symbols("aA"), symbols("aab"))
+	c = strict.Classify()
+	assert(t, c.Type == 1 && c.ContextSensitive, "aA → aab is context-sensitive")
+
+	erasing := NewUnrestrictedGrammar("S", "ε", []string{"S", "A"}, []string{"a"})
+	erasing.AddRule(symbols("S"), symbols("aSA"))
+	erasing.AddRule(symbols("aA"), []string{"ε"})
+	c = erasing.Classify()
+	assert(t, c.Type == 0, "aA → ε is contracting")
+	assert(t, c.Offending.String() == "a A → ε", "aA → ε should be reported")
+
+	cf := makeTestGrammar().ToUnrestricted().Classify()
+	assert(t, cf.Type == 2, "the lab5 grammar should be context-free")
+	assert(t, cf.Offending != nil, "the rule breaking right-linearity should be reported")
+
+	startEps := NewUnrestrictedGrammar("S", "ε", []string{"S", "A"}, []string{"a"})
+	startEps.AddRule(symbols("S"), []string{"ε"})
+	startEps.AddRule(symbols("S"), symbols("aA"))
+	startEps.AddRule(symbols("aA"), symbols("aa"))
+	assert(t, startEps.Classify().Type == 1, "S → ε is allowed when S is never on a right side")
+}
+
+func TestUnrestrictedMembership(t *testing.T) {
+	g := makeAnBnCn()
+
+	for _, w := range []string{"abc", "aabbcc", "aaabbbccc"} {
+		derivation, err := g.Derive(symbols(w), 100000)
+		if err != nil || derivation == nil {
+			t.Fatal("word: ", w, " Rejected ", err)
+		}
+		last := derivation[len(derivation)-1]
+		assert(t, strings.Join(last, "") == w, "derivation should end in the word")
+	}
+	for _, w := range []string{"", "ab", "aabbc", "abcabc", "aabcbc"} {
+		if ok, err := g.Member(symbols(w), 100000); err != nil || ok {
+			t.Fatal("word: ", w, " Accepted ", err)
+		}
+	}
+
+	// The search space of a noncontracting grammar is finite, so even a
+	// tiny bound gives an exact answer
+	if ok, err := g.Member(symbols("aabbcc"), 1); err != nil || !ok {
+		t.Fatal("word: aabbcc Rejected with a small bound ", err)
+	}
+
+	// S X^n rewrites forever, so a missing word can only be semi-decided
+	t0 := NewUnrestrictedGrammar("S", "ε", []string{"S", "X"}, []string{"a", "b"})
+	t0.AddRule(symbols("S"), symbols("SX"))
+	t0.AddRule(symbols("SX"), symbols("a"))
+	t0.AddRule(symbols("aX"), []string{"ε"})
+
+	if ok, err := t0.Member(symbols("a"), 1000); err != nil || !ok {
+		t.Fatal("word: a Rejected ", err)
+	}
+	if _, err := t0.Member(symbols("b"), 1000); err == nil {
+		t.Fatal("expected the search bound to be reported for a Type 0 grammar")
+	}
+}
@@ -0,0 +1,534 @@
+package lfa
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Semiring defines how weights combine along a path (Times) and across
+// alternative paths (Plus). Zero is the weight of "no path", One the weight
+// of the empty path.
+type Semiring[W comparable] interface {
+	Zero() W
+	One() W
+	Plus(a, b W) W
+	Times(a, b W) W
+}
+
+// OrderedSemiring is a semiring whose weights can be ranked, which is what
+// best-path search needs
+type OrderedSemiring[W comparable] interface {
+	Semiring[W]
+	Better(a, b W) bool
+}
+
+// BooleanSemiring ({false,true}, ∨, ∧) gives plain NFA acceptance
+type BooleanSemiring struct{}
+
+func (BooleanSemiring) Zero() bool            { return false }
+func (BooleanSemiring) One() bool             { return true }
+func (BooleanSemiring) Plus(a, b bool) bool   { return a || b }
+func (BooleanSemiring) Times(a, b bool) bool  { return a && b }
+func (BooleanSemiring) Better(a, b bool) bool { return a && !b }
+func (BooleanSemiring) String() string        { return "boolean" }
+
+// CountingSemiring (ℕ, +, ×) counts accepting paths
+type CountingSemiring struct{}
+
+func (CountingSemiring) Zero() int          { return 0 }
+func (CountingSemiring) One() int           { return 1 }
+func (CountingSemiring) Plus(a, b int) int  { return a + b }
+func (CountingSemiring) Times(a, b int) int { return a * b }
+func (CountingSemiring) String() string     { return "counting" }
+
+// TropicalSemiring (ℝ ∪ {+∞}, min, +) gives the cost of the cheapest path
+type TropicalSemiring struct{}
+
+func (TropicalSemiring) Zero() float64              { return math.Inf(1) }
+func (TropicalSemiring) One() float64               { return 0 }
+func (TropicalSemiring) Plus(a, b float64) float64  { return math.Min(a, b) }
+func (TropicalSemiring) Times(a, b float64) float64 { return a + b }
+func (TropicalSemiring) Better(a, b float64) bool   { return a < b }
+func (TropicalSemiring) String() string             { return "tropical" }
+
+// ProbabilitySemiring ([0,1], +, ×) gives the probability of generating a word
+type ProbabilitySemiring struct{}
+
+func (ProbabilitySemiring) Zero() float64              { return 0 }
+func (ProbabilitySemiring) One() float64               { return 1 }
+func (ProbabilitySemiring) Plus(a, b float64) float64  { return a + b }
+func (ProbabilitySemiring) Times(a, b float64) float64 { return a * b }
+func (ProbabilitySemiring) Better(a, b float64) bool   { return a > b }
+func (ProbabilitySemiring) String() string             { return "probability" }
+
+// DeltaWeighted maps a state and a label to the weighted set of target states
+type DeltaWeighted[W comparable] map[State]map[RuneRange]map[State]W
+
+// WeightedNFA is an NFA whose transitions, initial and final states carry
+// weights from a semiring K
+type WeightedNFA[W comparable] struct {
+	Q     []State
+	Sigma Alphabet
+	Delta DeltaWeighted[W]
+	Q0    map[State]W
+	F     map[State]W
+	K     Semiring[W]
+}
+
+// NewWeightedNFA creates an empty weighted automaton over the semiring k
+func NewWeightedNFA[W comparable](k Semiring[W], Q []State, Sigma Alphabet) *WeightedNFA[W] {
+	return &WeightedNFA[W]{
+		Q:     Q,
+		Sigma: Sigma,
+		Delta: make(DeltaWeighted[W]),
+		Q0:    make(map[State]W),
+		F:     make(map[State]W),
+		K:     k,
+	}
+}
+
+// WeightedFromNFA lifts an NFA into the semiring k, giving every transition,
+// initial and final state the weight One
+func WeightedFromNFA[W comparable](n *NFA, k Semiring[W]) *WeightedNFA[W] {
+	w := NewWeightedNFA(k, append([]State{}, n.Q...), append(Alphabet{}, n.Sigma...))
+
+	for _, q := range n.Q0 {
+		w.SetInitial(q, k.One())
+	}
+	for _, q := range n.F {
+		w.SetFinal(q, k.One())
+	}
+	for state, transitions := range n.Delta {
+		for symbol, nextStates := range transitions {
+			for nextState := range nextStates {
+				w.AddTransition(state, symbol, nextState, k.One())
+			}
+		}
+	}
+
+	return w
+}
+
+// SetInitial sets the initial weight of a state
+func (w *WeightedNFA[W]) SetInitial(q State, weight W) {
+	w.Q0[q] = weight
+}
+
+// SetFinal sets the final weight of a state
+func (w *WeightedNFA[W]) SetFinal(q State, weight W) {
+	w.F[q] = weight
+}
+
+// AddTransition adds a weighted edge; parallel edges with the same label are
+// merged with Plus
+func (w *WeightedNFA[W]) AddTransition(in State, r RuneRange, out State, weight W) {
+	if _, ok := w.Delta[in]; !ok {
+		w.Delta[in] = make(map[RuneRange]map[State]W)
+	}
+	if _, ok := w.Delta[in][r]; !ok {
+		w.Delta[in][r] = make(map[State]W)
+	}
+
+	if existing, ok := w.Delta[in][r][out]; ok {
+		weight = w.K.Plus(existing, weight)
+	}
+	w.Delta[in][r][out] = weight
+}
+
+// weightOf returns the weight stored for q, or Zero when it is absent
+func (w *WeightedNFA[W]) weightOf(m map[State]W, q State) W {
+	if weight, ok := m[q]; ok {
+		return weight
+	}
+	return w.K.Zero()
+}
+
+// epsilonClosure sums the weights of all epsilon paths leaving the vector v.
+// Propagation stops once no new weight arrives, which happens for acyclic
+// epsilon graphs and for idempotent semirings (boolean, tropical with
+// non-negative weights). An epsilon cycle in other semirings keeps adding
+// weight, e.g. one more path per round when counting, so an error is
+// returned if weight still arrives after |Q|+1 rounds.
+func (w *WeightedNFA[W]) epsilonClosure(v map[State]W) (map[State]W, error) {
+	k := w.K
+	result := make(map[State]W, len(v))
+	for q, weight := range v {
+		result[q] = weight
+	}
+
+	frontier := v
+	for round := 0; len(frontier) > 0; round++ {
+		if round > len(w.Q) {
+			return nil, fmt.Errorf("ε-closure did not converge in the %v semiring", k)
+		}
+
+		next := make(map[State]W)
+		for q, weight := range frontier {
+			for p, ew := range w.Delta[q][Epsilon] {
+				next[p] = k.Plus(w.weightOf(next, p), k.Times(weight, ew))
+			}
+		}
+
+		frontier = make(map[State]W)
+		for p, weight := range next {
+			updated := k.Plus(w.weightOf(result, p), weight)
+			if updated != w.weightOf(result, p) {
+				result[p] = updated
+				frontier[p] = weight
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// Weight computes the weight the automaton assigns to a word: the Plus over
+// all accepting paths labeled by the word of the Times along each path.
+// In the boolean semiring this is acceptance, in the counting semiring the
+// number of accepting paths, in the probability semiring the probability of
+// generating the word. An error is returned when an epsilon cycle keeps
+// adding weight, as when counting the paths of (a*)*.
+func (w *WeightedNFA[W]) Weight(word string) (W, error) {
+	k := w.K
+
+	current := make(map[State]W)
+	for q, weight := range w.Q0 {
+		current[q] = weight
+	}
+	current, err := w.epsilonClosure(current)
+	if err != nil {
+		return k.Zero(), err
+	}
+
+	for _, c := range word {
+		next := make(map[State]W)
+		for q, weight := range current {
+			for symbol, targets := range w.Delta[q] {
+				if !symbol.Contains(c) {
+					continue
+				}
+				for p, ew := range targets {
+					next[p] = k.Plus(w.weightOf(next, p), k.Times(weight, ew))
+				}
+			}
+		}
+
+		current, err = w.epsilonClosure(next)
+		if err != nil {
+			return k.Zero(), err
+		}
+		if len(current) == 0 {
+			return k.Zero(), nil
+		}
+	}
+
+	total := k.Zero()
+	for q, weight := range current {
+		if fw, ok := w.F[q]; ok {
+			total = k.Plus(total, k.Times(weight, fw))
+		}
+	}
+
+	return total, nil
+}
+
+// weightedEdge is a single transition of a weighted automaton
+type weightedEdge[W comparable] struct {
+	from   State
+	symbol RuneRange
+	to     State
+	weight W
+}
+
+// edges lists every transition of the automaton
+func (w *WeightedNFA[W]) edges() []weightedEdge[W] {
+	edges := make([]weightedEdge[W], 0)
+	for from, transitions := range w.Delta {
+		for symbol, targets := range transitions {
+			for to, weight := range targets {
+				edges = append(edges, weightedEdge[W]{from, symbol, to, weight})
+			}
+		}
+	}
+	return edges
+}
+
+// ShortestDistance computes the Plus over all accepting paths, regardless of
+// their labels (Mohri's generic single-source shortest-distance). In the
+// tropical semiring this is the cost of the cheapest accepted word.
+//
+// The result is exact for acyclic automata in any semiring and for cyclic
+// ones in k-closed semirings such as boolean or tropical with non-negative
+// weights; an error is returned if the relaxation does not converge.
+func (w *WeightedNFA[W]) ShortestDistance() (W, error) {
+	k := w.K
+	edges := w.edges()
+
+	out := make(map[State][]weightedEdge[W])
+	for _, e := range edges {
+		out[e.from] = append(out[e.from], e)
+	}
+
+	d := make(map[State]W)
+	r := make(map[State]W)
+	queue := make([]State, 0)
+	queued := make(map[State]bool)
+
+	for q, weight := range w.Q0 {
+		d[q] = weight
+		r[q] = weight
+		queue = append(queue, q)
+		queued[q] = true
+	}
+
+	limit := (len(w.Q) + 1) * (len(edges) + 1)
+	for relaxations := 0; len(queue) > 0; relaxations++ {
+		if relaxations > limit {
+			return k.Zero(), fmt.Errorf("shortest distance did not converge in the %v semiring", k)
+		}
+
+		q := queue[0]
+		queue = queue[1:]
+		queued[q] = false
+
+		residual := w.weightOf(r, q)
+		r[q] = k.Zero()
+
+		for _, e := range out[q] {
+			extended := k.Times(residual, e.weight)
+			updated := k.Plus(w.weightOf(d, e.to), extended)
+			if updated == w.weightOf(d, e.to) {
+				continue
+			}
+
+			d[e.to] = updated
+			r[e.to] = k.Plus(w.weightOf(r, e.to), extended)
+			if !queued[e.to] {
+				queue = append(queue, e.to)
+				queued[e.to] = true
+			}
+		}
+	}
+
+	total := k.Zero()
+	for q, weight := range d {
+		if fw, ok := w.F[q]; ok {
+			total = k.Plus(total, k.Times(weight, fw))
+		}
+	}
+
+	return total, nil
+}
+
+// BestPath finds the accepted word whose single best path has the best weight
+// according to the semiring order, e.g. the cheapest word in the tropical
+// semiring or the most likely derivation in the probability semiring. It
+// runs Dijkstra's algorithm, so extending a path must never improve it
+// (non-negative costs, probabilities at most 1). For range labels the
+// lowest rune of the range is used.
+func (w *WeightedNFA[W]) BestPath() (string, W, error) {
+	k, ok := w.K.(OrderedSemiring[W])
+	if !ok {
+		return "", w.K.Zero(), fmt.Errorf("best path needs an ordered semiring, got %v", w.K)
+	}
+
+	type step struct {
+		prev   State
+		symbol RuneRange
+	}
+
+	dist := make(map[State]W)
+	from := make(map[State]step)
+	done := make(map[State]bool)
+
+	for q, weight := range w.Q0 {
+		dist[q] = weight
+	}
+
+	bestFinal, bestWeight, found := "", k.Zero(), false
+
+	for {
+		// Pick the unsettled state with the best tentative weight
+		current, settled := "", false
+		for q, weight := range dist {
+			if done[q] {
+				continue
+			}
+			if !settled || k.Better(weight, dist[current]) || (weight == dist[current] && q < current) {
+				current, settled = q, true
+			}
+		}
+		if !settled {
+			break
+		}
+		done[current] = true
+
+		if fw, ok := w.F[current]; ok {
+			total := k.Times(dist[current], fw)
+			if !found || k.Better(total, bestWeight) {
+				bestFinal, bestWeight, found = current, total, true
+			}
+		}
+
+		for symbol, targets := range w.Delta[current] {
+			for p, ew := range targets {
+				if done[p] {
+					continue
+				}
+				candidate := k.Times(dist[current], ew)
+				if old, seen := dist[p]; !seen || k.Better(candidate, old) {
+					dist[p] = candidate
+					from[p] = step{current, symbol}
+				}
+			}
+		}
+	}
+
+	if !found {
+		return "", k.Zero(), fmt.Errorf("automaton accepts no word")
+	}
+
+	word := make([]rune, 0)
+	for q := bestFinal; ; {
+		s, ok := from[q]
+		if !ok {
+			break
+		}
+		if !s.symbol.IsEpsilon() {
+			word = append(word, s.symbol.Lo)
+		}
+		q = s.prev
+	}
+	for i, j := 0, len(word)-1; i < j; i, j = i+1, j-1 {
+		word[i], word[j] = word[j], word[i]
+	}
+
+	return string(word), bestWeight, nil
+}
+
+// NewProbabilisticNFA turns an NFA into a generative model: from every state
+// the next move is chosen uniformly among its outgoing transitions and, for
+// final states, stopping. Range labels count as a single move whose symbol is
+// drawn uniformly from the range.
+func NewProbabilisticNFA(n *NFA) *WeightedNFA[float64] {
+	p := NewWeightedNFA[float64](ProbabilitySemiring{}, append([]State{}, n.Q...), append(Alphabet{}, n.Sigma...))
+
+	for _, q := range n.Q0 {
+		p.SetInitial(q, 1/float64(len(n.Q0)))
+	}
+
+	for _, q := range n.Q {
+		moves := 0
+		for _, nextStates := range n.Delta[q] {
+			moves += len(nextStates)
+		}
+		final := contains(n.F, q)
+		if final {
+			moves++
+		}
+		if moves == 0 {
+			continue
+		}
+
+		share := 1 / float64(moves)
+		if final {
+			p.SetFinal(q, share)
+		}
+		for symbol, nextStates := range n.Delta[q] {
+			perRune := share
+			if !symbol.IsEpsilon() {
+				perRune = share / float64(symbol.Size())
+			}
+			for nextState := range nextStates {
+				p.AddTransition(q, symbol, nextState, perRune)
+			}
+		}
+	}
+
+	return p
+}
+
+// SampleWord draws a word from a probabilistic automaton, following initial,
+// transition and final weights as probabilities. Weights of a range label are
+// per rune. It returns the word together with the probability of the path
+// that produced it.
+func SampleWord(p *WeightedNFA[float64], maxLength int) (string, float64, error) {
+	type move struct {
+		stop   bool
+		symbol RuneRange
+		state  State
+		weight float64
+	}
+
+	pick := func(weights []float64) int {
+		total := 0.0
+		for _, w := range weights {
+			total += w
+		}
+		x := rand.Float64() * total
+		for i, w := range weights {
+			if x < w {
+				return i
+			}
+			x -= w
+		}
+		return len(weights) - 1
+	}
+
+	initial := make([]State, 0, len(p.Q0))
+	for q := range p.Q0 {
+		initial = append(initial, q)
+	}
+	sort.Strings(initial)
+	if len(initial) == 0 {
+		return "", 0, fmt.Errorf("automaton has no initial state")
+	}
+
+	weights := make([]float64, len(initial))
+	for i, q := range initial {
+		weights[i] = p.Q0[q]
+	}
+	i := pick(weights)
+	state, probability := initial[i], p.Q0[initial[i]]
+	word := make([]rune, 0)
+
+	for steps := 0; ; steps++ {
+		moves := make([]move, 0)
+		weights := make([]float64, 0)
+
+		if fw, ok := p.F[state]; ok && fw > 0 {
+			moves = append(moves, move{true, Epsilon, state, fw})
+			weights = append(weights, fw)
+		}
+		for symbol, targets := range p.Delta[state] {
+			for next, w := range targets {
+				mass := w * float64(max(symbol.Size(), 1))
+				moves = append(moves, move{false, symbol, next, w})
+				weights = append(weights, mass)
+			}
+		}
+
+		if len(moves) == 0 {
+			return "", 0, fmt.Errorf("reached dead end in state %s", state)
+		}
+		if steps > 4*maxLength+len(p.Q) {
+			return "", 0, fmt.Errorf("no word generated within length %d", maxLength)
+		}
+
+		m := moves[pick(weights)]
+		probability *= m.weight
+		if m.stop {
+			return string(word), probability, nil
+		}
+
+		if !m.symbol.IsEpsilon() {
+			if len(word) >= maxLength {
+				return "", 0, fmt.Errorf("no word generated within length %d", maxLength)
+			}
+			word = append(word, m.symbol.Lo+rune(rand.Intn(m.symbol.Size())))
+		}
+		state = m.state
+	}
+}
@@ -0,0 +1,117 @@
+package lfa
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWeightedSemirings(t *testing.T) {
+	// (a|ab)(b|ε) is ambiguous on "ab": two accepting paths
+	nfa, err := CreateNFAFromRegex("(a|ab)(b|)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accept := WeightedFromNFA(nfa, BooleanSemiring{})
+	for _, w := range []string{"a", "ab", "abb"} {
+		if ok, err := accept.Weight(w); err != nil || !ok {
+			t.Fatal("word: ", w, " Rejected ", err)
+		}
+	}
+	if ok, _ := accept.Weight("b"); ok {
+		t.Fatal("word: b Accepted")
+	}
+
+	count := WeightedFromNFA(nfa, CountingSemiring{})
+	if got, err := count.Weight("ab"); err != nil || got != 2 {
+		t.Fatalf("expected 2 accepting paths for ab, got %d (%v)", got, err)
+	}
+	if got, err := count.Weight("abb"); err != nil || got != 1 {
+		t.Fatalf("expected 1 accepting path for abb, got %d (%v)", got, err)
+	}
+}
+
+func TestWeightEpsilonCycle(t *testing.T) {
+	// The ε-cycles of (a*)* give infinitely many accepting paths
+	nfa, err := CreateNFAFromRegex("(a*)*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := WeightedFromNFA(nfa, CountingSemiring{}).Weight("aa"); err == nil {
+		t.Error("expected an error when counting the paths of an ε-cycle")
+	}
+	// Idempotent semirings are not affected by the cycles
+	if ok, err := WeightedFromNFA(nfa, BooleanSemiring{}).Weight("aa"); err != nil || !ok {
+		t.Errorf("boolean weight of aa: %v (%v)", ok, err)
+	}
+	if cost, err := WeightedFromNFA(nfa, TropicalSemiring{}).Weight("aa"); err != nil || cost != 0 {
+		t.Errorf("tropical weight of aa: %v (%v)", cost, err)
+	}
+}
+
+func TestTropicalBestPath(t *testing.T) {
+	w := NewWeightedNFA[float64](TropicalSemiring{}, []State{"q0", "q1", "q2"}, NewAlphabet('a', 'b'))
+	w.SetInitial("q0", 0)
+	w.SetFinal("q2", 0)
+	w.AddTransition("q0", Sym('a'), "q2", 5)
+	w.AddTransition("q0", Sym('b'), "q1", 1)
+	w.AddTransition("q1", Sym('a'), "q2", 1)
+	w.AddTransition("q1", Sym('b'), "q1", 3)
+
+	if got, _ := w.Weight("a"); got != 5 {
+		t.Fatalf("cost of a: got %v, want 5", got)
+	}
+	if got, _ := w.Weight("bba"); got != 5 {
+		t.Fatalf("cost of bba: got %v, want 5", got)
+	}
+	if got, _ := w.Weight("bb"); !math.IsInf(got, 1) {
+		t.Fatalf("cost of rejected word should be +Inf, got %v", got)
+	}
+
+	word, cost, err := w.BestPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if word != "ba" || cost != 2 {
+		t.Fatalf("best path: got %q with cost %v, want \"ba\" with cost 2", word, cost)
+	}
+
+	d, err := w.ShortestDistance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d != cost {
+		t.Fatalf("shortest distance %v disagrees with best path cost %v", d, cost)
+	}
+}
+
+func TestProbabilisticNFA(t *testing.T) {
+	delta := make(DeltaNfa)
+	delta.Add("q0", Sym('a'), NewSetState("q1"))
+	delta.Add("q1", Sym('b'), NewSetState("q1"))
+	delta.Add("q1", Sym('c'), NewSetState("q1"))
+	nfa := NewNFA([]State{"q0", "q1"}, NewAlphabet('a', 'b', 'c'), delta, []State{"q0"}, []State{"q1"})
+	p := NewProbabilisticNFA(nfa)
+
+	// From q1 the model stops, reads b or reads c with probability 1/3 each
+	if got, _ := p.Weight("a"); math.Abs(got-1.0/3) > 1e-9 {
+		t.Fatalf("P(a) = %v, want 1/3", got)
+	}
+	if got, _ := p.Weight("abc"); math.Abs(got-1.0/27) > 1e-9 {
+		t.Fatalf("P(abc) = %v, want 1/27", got)
+	}
+
+	for i := 0; i < 20; i++ {
+		word, prob, err := SampleWord(p, 20)
+		if err != nil {
+			continue
+		}
+		if !nfa.Accept(word) {
+			t.Fatal("sampled word: ", word, " Rejected")
+		}
+		if weight, _ := p.Weight(word); math.Abs(prob-weight) > 1e-9 {
+			t.Fatalf("path probability %v differs from P(%s) = %v", prob, word, weight)
+		}
+	}
+}